@@ -0,0 +1,417 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/castiglionimax/process-csv/internal/controller"
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+// contractService answers every call with a sample covering the optional
+// fields of the responses, or with err when set.
+type contractService struct {
+	controller.Service
+	err error
+}
+
+var (
+	sampleTime        = time.Date(2023, 10, 14, 18, 30, 0, 0, time.UTC)
+	sampleTransaction = domain.Transaction{
+		AccountID: "acc", Date: sampleTime, Amount: -40, Type: domain.TransactionTransfer,
+		Description: "rent share", Merchant: "bank", Category: "rent", Reference: "ref-1",
+		TransferID: "tr-1", Counterparty: "other",
+	}
+	sampleViolation = domain.Violation{Transaction: sampleTransaction, Policy: "overdraft", Reason: "balance below the limit", Balance: -10}
+	sampleAccount   = domain.Account{
+		ID: "acc", Name: "Juan", Email: "juan@example.com", Locale: "en", Status: domain.AccountActive,
+		Timezone: "America/Argentina/Buenos_Aires", Channels: []domain.Channel{domain.ChannelEmail, domain.ChannelWebhook},
+		WebhookURL: "https://example.com/hook", OverdraftPolicy: domain.OverdraftFlag, OverdraftLimit: 100,
+	}
+	sampleRule = domain.CategoryRule{
+		ID: "rule", AccountID: "acc", Field: domain.RuleFieldMerchant, Match: domain.RuleMatchContains,
+		Pattern: "cafe", Category: "coffee", Priority: 1, CreatedAt: sampleTime, UpdatedAt: sampleTime,
+	}
+)
+
+func (s contractService) CreateAccount(context.Context, domain.Account) (domain.AccountID, error) {
+	return "acc", s.err
+}
+
+func (s contractService) SaveTransactions(context.Context, []domain.Transaction) error {
+	return s.err
+}
+
+func (s contractService) ProcessFiles(context.Context) (domain.ProcessingReport, error) {
+	return domain.ProcessingReport{Received: 2, Accepted: 1, Rejected: []domain.Violation{sampleViolation}}, s.err
+}
+
+func (s contractService) ReverseTransaction(context.Context, string, string) (string, error) {
+	return "reversal", s.err
+}
+
+func (s contractService) CreateTransfer(context.Context, domain.Transfer) (string, error) {
+	return "tr-1", s.err
+}
+
+func (s contractService) SendSummary(context.Context, domain.AccountID, domain.Granularity, time.Time, time.Time) ([]string, error) {
+	return []string{"n-1", "n-2"}, s.err
+}
+
+func (s contractService) UpdatePreferences(context.Context, domain.Preferences) error {
+	return s.err
+}
+
+func (s contractService) UpdatePolicy(context.Context, domain.Policy) error {
+	return s.err
+}
+
+func (s contractService) GetFlags(context.Context, domain.AccountID, time.Time, time.Time) ([]domain.Flag, error) {
+	return []domain.Flag{{EventID: "event", Time: sampleTime, Violation: sampleViolation}}, s.err
+}
+
+func (s contractService) GetNotification(context.Context, string) (domain.Notification, error) {
+	return domain.Notification{
+		ID: "n-1", AccountID: "acc", Channel: domain.ChannelEmail, Recipient: "juan@example.com", Subject: "Your summary",
+		Status: domain.NotificationFailed, Attempts: 3, LastError: "mailbox full", CreatedAt: sampleTime, UpdatedAt: sampleTime,
+	}, s.err
+}
+
+func (s contractService) GetAccount(context.Context, domain.AccountID) (domain.Account, error) {
+	return sampleAccount, s.err
+}
+
+func (s contractService) ListAccounts(context.Context, domain.AccountFilter) (domain.AccountPage, error) {
+	return domain.AccountPage{Items: []domain.Account{sampleAccount}, NextCursor: "next"}, s.err
+}
+
+func (s contractService) GetBalance(context.Context, domain.AccountID) (domain.Balance, error) {
+	return domain.Balance{AccountID: "acc", Amount: 60, LastUpdated: sampleTime}, s.err
+}
+
+func (s contractService) GetSummaries(context.Context, domain.AccountID, domain.Granularity, time.Time, time.Time) ([]domain.Summary, error) {
+	return []domain.Summary{{
+		Period: "2023-10", Credit: 100, CreditQty: 1, Debit: -40, DebitQty: 1, TransferIn: 10, TransferInQty: 1,
+		TransferOut: -10, TransferOutQty: 1, MinCredit: 100, MaxCredit: 100, MinDebit: 40, MaxDebit: 40,
+		OpeningBalance: 0, ClosingBalance: 60, LastUpdated: sampleTime,
+	}}, s.err
+}
+
+func (s contractService) GetTransactions(context.Context, domain.AccountID, domain.TransactionFilter) (domain.TransactionPage, error) {
+	return domain.TransactionPage{Items: []domain.TransactionEvent{{
+		EventID: "event", EventType: "debit", AggregateID: "acc", Time: sampleTime, Data: sampleTransaction, ReversedBy: "reversal",
+	}}, NextCursor: "next"}, s.err
+}
+
+func (s contractService) ExportSummary(_ context.Context, _ domain.AccountID, _ domain.Granularity, _, _ time.Time, format domain.ReportFormat) (domain.Report, error) {
+	if format == domain.ReportCSV {
+		return domain.Report{Filename: "summary.csv", ContentType: "text/csv", Content: []byte("period,credit\n2023-10,100\n")}, s.err
+	}
+	return domain.Report{Filename: "summary.json", ContentType: "application/json", Content: []byte(`{"account_id":"acc"}`)}, s.err
+}
+
+func (s contractService) CreateCategoryRule(context.Context, domain.CategoryRule) (domain.CategoryRule, error) {
+	return sampleRule, s.err
+}
+
+func (s contractService) UpdateCategoryRule(context.Context, domain.CategoryRule) (domain.CategoryRule, error) {
+	return sampleRule, s.err
+}
+
+func (s contractService) DeleteCategoryRule(context.Context, string) error {
+	return s.err
+}
+
+func (s contractService) GetCategoryRule(context.Context, string) (domain.CategoryRule, error) {
+	return sampleRule, s.err
+}
+
+func (s contractService) ListCategoryRules(context.Context, domain.AccountID) ([]domain.CategoryRule, error) {
+	return []domain.CategoryRule{sampleRule}, s.err
+}
+
+// contractRouter maps the routes the way the server does, on top of service.
+func contractRouter(t *testing.T, service controller.Service) *chi.Mux {
+	t.Helper()
+	c, err := controller.NewController(service, nil)
+	if err != nil {
+		t.Fatalf("NewController: %v", err)
+	}
+	router := chi.NewRouter()
+	mapping{controller: *c}.mapUrlsToControllers(router)
+	return router
+}
+
+// csvUpload is a multipart body carrying file in the csv field.
+func csvUpload(t *testing.T, field, file string) (string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, "transactions.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = part.Write([]byte(file))
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return writer.FormDataContentType(), body.String()
+}
+
+func TestContract(t *testing.T) {
+	spec, err := loadSpec()
+	if err != nil {
+		t.Fatalf("reading the spec: %v", err)
+	}
+	uploadType, upload := csvUpload(t, "csv", "account_id,date,amount\nacc,2023-10-14,-40\n")
+	wrongFieldType, wrongField := csvUpload(t, "file", "acc,2023-10-14,-40\n")
+
+	const (
+		account  = `{"name":"Juan","email":"juan@example.com","locale":"es","timezone":"UTC","channels":["webhook"],"webhook_url":"https://example.com/hook","overdraft_policy":"reject","overdraft_limit":50}`
+		transfer = `{"from_account_id":"acc","to_account_id":"other","amount":10.5,"date":"2023-10-14T18:30:00Z","description":"rent share","reference":"ref-1"}`
+		rule     = `{"account_id":"acc","field":"merchant","match":"regex","pattern":"^cafe","category":"coffee","priority":2}`
+	)
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		err         error
+		status      int
+	}{
+		{name: "ping", method: http.MethodGet, target: "/ping", status: http.StatusOK},
+		{name: "spec", method: http.MethodGet, target: "/openapi.json", status: http.StatusOK},
+
+		{name: "create account", method: http.MethodPost, target: "/accounts", body: account, status: http.StatusCreated},
+		{name: "create account malformed", method: http.MethodPost, target: "/accounts", body: `{"name":`, status: http.StatusBadRequest},
+		{name: "create account invalid", method: http.MethodPost, target: "/accounts", body: `{"name":"Juan","email":"juan"}`, status: http.StatusUnprocessableEntity},
+		{name: "create account duplicate", method: http.MethodPost, target: "/accounts", body: account, err: pkgError.ErrDuplicateEmail, status: http.StatusConflict},
+		{name: "create account failing", method: http.MethodPost, target: "/accounts", body: account, err: errors.New("boom"), status: http.StatusInternalServerError},
+		{name: "list accounts", method: http.MethodGet, target: "/accounts?status=active&limit=10&cursor=abc", status: http.StatusOK},
+		{name: "list accounts bad limit", method: http.MethodGet, target: "/accounts?limit=0", status: http.StatusBadRequest},
+		{name: "list accounts unavailable", method: http.MethodGet, target: "/accounts", err: pkgError.ErrUnavailable, status: http.StatusServiceUnavailable},
+		{name: "get account", method: http.MethodGet, target: "/accounts/acc", status: http.StatusOK},
+		{name: "get account missing", method: http.MethodGet, target: "/accounts/acc", err: pkgError.NotFound("account"), status: http.StatusNotFound},
+
+		{name: "preferences", method: http.MethodPut, target: "/accounts/acc/preferences", body: `{"channels":["email","webhook"],"webhook_url":"https://example.com/hook"}`, status: http.StatusAccepted},
+		{name: "preferences invalid", method: http.MethodPut, target: "/accounts/acc/preferences", body: `{"channels":["webhook"]}`, status: http.StatusUnprocessableEntity},
+		{name: "preferences missing account", method: http.MethodPut, target: "/accounts/acc/preferences", body: `{"channels":["email"]}`, err: pkgError.NotFound("account"), status: http.StatusNotFound},
+		{name: "policy", method: http.MethodPut, target: "/accounts/acc/policy", body: `{"overdraft_policy":"reject","overdraft_limit":25}`, status: http.StatusAccepted},
+		{name: "policy invalid", method: http.MethodPut, target: "/accounts/acc/policy", body: `{"overdraft_policy":"never"}`, status: http.StatusUnprocessableEntity},
+
+		{name: "balance", method: http.MethodGet, target: "/accounts/acc/balance", status: http.StatusOK},
+		{name: "balance missing account", method: http.MethodGet, target: "/accounts/acc/balance", err: pkgError.NotFound("account"), status: http.StatusNotFound},
+		{name: "summaries", method: http.MethodGet, target: "/accounts/acc/summaries?start=2023-10-01&end=2023-11-01&granularity=week", status: http.StatusOK},
+		{name: "summaries bad granularity", method: http.MethodGet, target: "/accounts/acc/summaries?granularity=decade", status: http.StatusBadRequest},
+		{name: "export summary", method: http.MethodGet, target: "/accounts/acc/summary", status: http.StatusOK},
+		{name: "export summary as csv", method: http.MethodGet, target: "/accounts/acc/summary?format=csv", status: http.StatusOK},
+		{name: "export summary bad format", method: http.MethodGet, target: "/accounts/acc/summary?format=xls", status: http.StatusBadRequest},
+		{name: "email summary", method: http.MethodPost, target: "/accounts/acc/summary/email?granularity=month", status: http.StatusAccepted},
+		{name: "email summary missing account", method: http.MethodPost, target: "/accounts/acc/summary/email", err: pkgError.NotFound("account"), status: http.StatusNotFound},
+		{name: "transactions", method: http.MethodGet, target: "/accounts/acc/transactions?type=transfer&limit=5", status: http.StatusOK},
+		{name: "transactions bad cursor", method: http.MethodGet, target: "/accounts/acc/transactions?cursor=x", err: pkgError.ErrInvalidCursor, status: http.StatusBadRequest},
+		{name: "flags", method: http.MethodGet, target: "/accounts/acc/flags", status: http.StatusOK},
+		{name: "flags bad start", method: http.MethodGet, target: "/accounts/acc/flags?start=yesterday", status: http.StatusBadRequest},
+
+		{name: "upload", method: http.MethodPost, target: "/csv/upload", contentType: uploadType, body: upload, status: http.StatusOK},
+		{name: "upload without the csv field", method: http.MethodPost, target: "/csv/upload", contentType: wrongFieldType, body: wrongField, status: http.StatusBadRequest},
+		{name: "upload unknown profile", method: http.MethodPost, target: "/csv/upload?profile=unknown", contentType: uploadType, body: upload, status: http.StatusBadRequest},
+		{name: "create csv", method: http.MethodPost, target: "/csv", body: `[{"account_id":"acc","timestamp":1697308200,"amount":-40,"type":"debit","description":"rent","merchant":"bank","category":"rent","reference":"ref-1"},{"account_id":"acc","timestamp":"1697308200","amount":"12.5"}]`, status: http.StatusOK},
+		{name: "create csv invalid", method: http.MethodPost, target: "/csv", body: `[{"account_id":"","timestamp":1697308200,"amount":0}]`, status: http.StatusUnprocessableEntity},
+		{name: "process", method: http.MethodPost, target: "/csv/process", status: http.StatusOK},
+		{name: "process in progress", method: http.MethodPost, target: "/csv/process", err: pkgError.ErrProcessingInProgress, status: http.StatusConflict},
+
+		{name: "reverse", method: http.MethodPost, target: "/transactions/event/reverse", body: `{"reason":"charged twice"}`, status: http.StatusAccepted},
+		{name: "reverse without reason", method: http.MethodPost, target: "/transactions/event/reverse", status: http.StatusAccepted},
+		{name: "reverse twice", method: http.MethodPost, target: "/transactions/event/reverse", err: pkgError.ErrAlreadyReversed, status: http.StatusConflict},
+		{name: "transfer", method: http.MethodPost, target: "/transfers", body: transfer, status: http.StatusCreated},
+		{name: "transfer invalid", method: http.MethodPost, target: "/transfers", body: `{"from_account_id":"acc","to_account_id":"acc","amount":-1}`, status: http.StatusUnprocessableEntity},
+		{name: "transfer duplicate", method: http.MethodPost, target: "/transfers", body: transfer, err: pkgError.ErrDuplicateTransfer, status: http.StatusConflict},
+
+		{name: "notification", method: http.MethodGet, target: "/notifications/n-1", status: http.StatusOK},
+		{name: "notification missing", method: http.MethodGet, target: "/notifications/n-1", err: pkgError.NotFound("notification"), status: http.StatusNotFound},
+
+		{name: "create rule", method: http.MethodPost, target: "/categories/rules", body: rule, status: http.StatusCreated},
+		{name: "create rule invalid", method: http.MethodPost, target: "/categories/rules", body: `{"field":"merchant","match":"regex","pattern":"(","category":""}`, status: http.StatusUnprocessableEntity},
+		{name: "list rules", method: http.MethodGet, target: "/categories/rules?account_id=acc", status: http.StatusOK},
+		{name: "get rule", method: http.MethodGet, target: "/categories/rules/rule", status: http.StatusOK},
+		{name: "get rule missing", method: http.MethodGet, target: "/categories/rules/rule", err: pkgError.NotFound("category rule"), status: http.StatusNotFound},
+		{name: "update rule", method: http.MethodPut, target: "/categories/rules/rule", body: rule, status: http.StatusOK},
+		{name: "update rule invalid", method: http.MethodPut, target: "/categories/rules/rule", body: `{"field":"amount","pattern":"cafe","category":"coffee"}`, status: http.StatusUnprocessableEntity},
+		{name: "delete rule", method: http.MethodDelete, target: "/categories/rules/rule", status: http.StatusNoContent},
+		{name: "delete rule missing", method: http.MethodDelete, target: "/categories/rules/rule", err: pkgError.NotFound("category rule"), status: http.StatusNotFound},
+	}
+
+	succeeded := make(map[string]bool)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := contractRouter(t, contractService{err: tt.err})
+			rctx := chi.NewRouteContext()
+			if !router.Match(rctx, tt.method, strings.Split(tt.target, "?")[0]) {
+				t.Fatalf("%s %s is not routed", tt.method, tt.target)
+			}
+			route := tt.method + " " + rctx.RoutePattern()
+			operation, ok := spec.operation(tt.method, rctx.RoutePattern())
+			if !ok {
+				t.Fatalf("%s is not in the spec", route)
+			}
+			if tt.status < http.StatusMultipleChoices {
+				validateRequest(t, spec, operation, tt.contentType, tt.body)
+			}
+
+			request := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", recorder.Code, tt.status, recorder.Body)
+			}
+			validateResponse(t, spec, operation, recorder)
+			if recorder.Code < http.StatusMultipleChoices {
+				succeeded[route] = true
+			}
+		})
+	}
+
+	for _, route := range spec.operations() {
+		if !succeeded[route] {
+			t.Errorf("no successful call of %s checked against the spec", route)
+		}
+	}
+}
+
+// validateRequest checks that the body of a successful case is one the spec
+// documents, so the cases double as examples of the requests. The failing
+// cases send bodies outside the spec on purpose.
+func validateRequest(t *testing.T, spec specDocument, operation map[string]any, contentType, body string) {
+	t.Helper()
+	requestBody, ok := operation["requestBody"].(map[string]any)
+	if !ok {
+		if body != "" {
+			t.Fatalf("sends a body, the spec documents none")
+		}
+		return
+	}
+	if body == "" {
+		if required, _ := requestBody["required"].(bool); required {
+			t.Fatalf("sends no body, the spec requires one")
+		}
+		return
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	content, _ := requestBody["content"].(map[string]any)
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		t.Fatalf("sends %s, the spec documents %v", mediaType, keys(content))
+	}
+	if mediaType != "application/json" {
+		return
+	}
+	var value any
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		t.Fatalf("request body is not JSON: %v", err)
+	}
+	schema, _ := media["schema"].(map[string]any)
+	for _, problem := range spec.validate("request", schema, value) {
+		t.Errorf("%s", problem)
+	}
+}
+
+// validateResponse checks that the status, content type and body of the
+// response are documented by the operation.
+func validateResponse(t *testing.T, spec specDocument, operation map[string]any, recorder *httptest.ResponseRecorder) {
+	t.Helper()
+	responses, _ := operation["responses"].(map[string]any)
+	documented, ok := responses[strconv.Itoa(recorder.Code)].(map[string]any)
+	if !ok {
+		t.Fatalf("status %d is not documented, the spec has %v", recorder.Code, keys(responses))
+	}
+	response, err := spec.resolve(documented)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, ok := response["content"].(map[string]any)
+	if !ok {
+		if recorder.Body.Len() > 0 {
+			t.Fatalf("answers a body, the spec documents none: %s", recorder.Body)
+		}
+		return
+	}
+	mediaType, _, err := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("content type %q: %v", recorder.Header().Get("Content-Type"), err)
+	}
+	media, ok := content[mediaType].(map[string]any)
+	if !ok {
+		t.Fatalf("answers %s, the spec documents %v", mediaType, keys(content))
+	}
+	schema, _ := media["schema"].(map[string]any)
+
+	var value any
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if err := json.Unmarshal(recorder.Body.Bytes(), &value); err != nil {
+			t.Fatalf("body is not JSON: %v: %s", err, recorder.Body)
+		}
+	default:
+		value = recorder.Body.String()
+	}
+	for _, problem := range spec.validate("response", schema, value) {
+		t.Errorf("%s", problem)
+	}
+}
+
+// TestSpecRoutes checks that the spec documents every route mapped, and maps
+// every route documented.
+func TestSpecRoutes(t *testing.T) {
+	spec, err := loadSpec()
+	if err != nil {
+		t.Fatalf("reading the spec: %v", err)
+	}
+
+	var mapped []string
+	walk := func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		mapped = append(mapped, method+" "+route)
+		return nil
+	}
+	if err = chi.Walk(contractRouter(t, contractService{}), walk); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	sort.Strings(mapped)
+
+	documented := spec.operations()
+	if strings.Join(mapped, "\n") != strings.Join(documented, "\n") {
+		t.Errorf("routes differ from the spec\nmapped:\n%s\ndocumented:\n%s", strings.Join(mapped, "\n"), strings.Join(documented, "\n"))
+	}
+}
+
+func keys(object map[string]any) []string {
+	var names []string
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,10 +2,8 @@ package server
 
 import (
 	"context"
-	"crypto/tls"
 	"database/sql"
 	"fmt"
-	"github.com/harranali/mailing"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"log"
@@ -21,23 +19,55 @@ import (
 	"github.com/castiglionimax/process-csv/internal/controller"
 	"github.com/castiglionimax/process-csv/internal/repository"
 	"github.com/castiglionimax/process-csv/internal/service"
+	"github.com/castiglionimax/process-csv/pkg/email"
+	"github.com/castiglionimax/process-csv/pkg/ingest"
 )
 
-func resolveController() controller.Controller {
-	ctr, _ := controller.NewController(resolverService())
+func resolveController(srv *service.Service) controller.Controller {
+	ctr, _ := controller.NewController(srv, resolverImportProfiles())
 	return *ctr
 }
 
+// resolverImportProfiles adds the profiles of the JSON file in
+// IMPORT_PROFILES_FILE, if any, to the built-in ones.
+func resolverImportProfiles() ingest.Profiles {
+	profiles, err := ingest.LoadProfiles(os.Getenv("IMPORT_PROFILES_FILE"))
+	if err != nil {
+		log.Fatalf("import profiles: %v", err)
+	}
+	return profiles
+}
+
 func resolverService() *service.Service {
 	srv, _ := service.NewService(
 		repository.NewRepository(resolverQueueProducer(),
 			"EventQueue",
 			resolveEventStore(),
 			resolverRelationDatabase(),
-			resolverObjectStorage(), resolverSmtpServer()))
+			resolverObjectStorage(), resolverTemplates()),
+		resolverNotifiers()...)
 	return srv
 }
 
+func resolverNotifiers() []service.Notifier {
+	notifiers := []service.Notifier{repository.NewEmailNotifier(resolverSmtpServer())}
+
+	client := repository.NewWebhookClient(10 * time.Second)
+	if webhookNotifier, err := repository.NewWebhookNotifier(client, os.Getenv("WEBHOOK_SECRET")); err != nil {
+		log.Printf("webhook channel disabled, set WEBHOOK_SECRET to enable it: %v", err)
+	} else {
+		notifiers = append(notifiers, webhookNotifier)
+	}
+	if dir := os.Getenv("NOTIFICATION_SPOOL_DIR"); dir != "" {
+		fileNotifier, err := repository.NewFileNotifier(dir)
+		if err != nil {
+			log.Fatalf("notification spool error: %v", err)
+		}
+		notifiers = append(notifiers, fileNotifier)
+	}
+	return notifiers
+}
+
 func resolverQueueProducer() *kafka.Producer {
 	uri := os.Getenv("KAFKA_URI")
 	producer, err := kafka.NewProducer(&kafka.ConfigMap{
@@ -68,7 +98,8 @@ func resolverQueueConsumer(groupId string) *kafka.Consumer {
 }
 
 func resolveEventStore() *mongo.Client {
-	ctx, _ := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
 	uri := os.Getenv("MONGO_URI")
 	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
@@ -131,15 +162,27 @@ func resolverObjectStorage() *minio.Client {
 	return minioClient
 }
 
-func resolverSmtpServer() *mailing.Mailer {
-	return mailing.NewMailerWithSMTP(&mailing.SMTPConfig{
-		Host:     "smtp4dev",
-		Port:     25,
-		Username: "",
-		Password: "",
-		TLSConfig: tls.Config{
-			ServerName:         "smtp4dev",
-			InsecureSkipVerify: true,
-		},
-	})
+func resolverSmtpServer() *email.SMTPTransport {
+	config, err := email.ConfigFromEnv(os.Getenv)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	transport := email.NewSMTPTransport(config)
+
+	if os.Getenv("SMTP_SKIP_CHECK") != "true" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err = transport.Check(ctx); err != nil {
+			log.Fatalf("smtp check error: %v", err)
+		}
+	}
+	return transport
+}
+
+func resolverTemplates() *repository.Renderer {
+	renderer, err := repository.NewRenderer(os.Getenv("TEMPLATES_DIR"))
+	if err != nil {
+		log.Fatalf("loading templates: %v", err)
+	}
+	return renderer
 }
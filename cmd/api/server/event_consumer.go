@@ -23,10 +23,13 @@ type (
 )
 
 const (
-	createAccount = "account_created"
+	createAccount     = "account_created"
+	updatePreferences = "account_preferences_updated"
+	updatePolicy      = "account_policy_updated"
 
 	saveDebit  = "debit_saved"
 	saveCredit = "credit_saved"
+	reverseTx  = "transaction_reversed"
 )
 
 func newConsumerEvent(consumer *kafka.Consumer) *eventConsumer {
@@ -77,12 +80,27 @@ func (c eventConsumer) HandlerAccount() {
 				err = retry(func() error {
 					return c.EventHandler.SaveAccount(context.TODO(), req)
 				})
+			case updatePreferences:
+				req, _ := json.Marshal(body.Data)
+				err = retry(func() error {
+					return c.EventHandler.UpdatePreferences(context.TODO(), req)
+				})
+			case updatePolicy:
+				req, _ := json.Marshal(body.Data)
+				err = retry(func() error {
+					return c.EventHandler.UpdatePolicy(context.TODO(), req)
+				})
 			case saveCredit, saveDebit:
 				log.Printf("paso por aca")
 				req, _ := json.Marshal(body.Data)
 				err = retry(func() error {
 					return c.EventHandler.RegisterTransaction(context.TODO(), req)
 				})
+			case reverseTx:
+				req, _ := json.Marshal(body.Data)
+				err = retry(func() error {
+					return c.EventHandler.ReverseTransaction(context.TODO(), req)
+				})
 			default:
 				log.Printf("invalid transaction type")
 				continue
@@ -148,7 +166,12 @@ func (c eventConsumer) HandlerSummary() {
 			case saveCredit, saveDebit:
 				req, _ := json.Marshal(body.Data)
 				err = retry(func() error {
-					return c.EventHandler.RegisterSummary(context.TODO(), req)
+					return c.EventHandler.RegisterSummary(context.TODO(), body.EventID, req)
+				})
+			case reverseTx:
+				req, _ := json.Marshal(body.Data)
+				err = retry(func() error {
+					return c.EventHandler.ReverseSummary(context.TODO(), req)
 				})
 			default:
 				log.Printf("invalid transaction type")
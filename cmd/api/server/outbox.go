@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultOutboxInterval = 5 * time.Second
+	outboxBatch           = 20
+)
+
+type (
+	notificationDispatcher interface {
+		DispatchNotifications(ctx context.Context, batch int) error
+	}
+
+	outboxSender struct {
+		interval   time.Duration
+		dispatcher notificationDispatcher
+	}
+)
+
+func newOutboxSender(dispatcher notificationDispatcher) *outboxSender {
+	interval := defaultOutboxInterval
+	if value := os.Getenv("OUTBOX_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("outbox: bad OUTBOX_INTERVAL: %v", err)
+		}
+		interval = parsed
+	}
+	return &outboxSender{interval: interval, dispatcher: dispatcher}
+}
+
+func (o outboxSender) Run() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case sig := <-signals:
+			log.Printf("outbox: stopping on %v", sig)
+			return
+		case <-ticker.C:
+			if err := o.dispatcher.DispatchNotifications(context.Background(), outboxBatch); err != nil {
+				log.Printf("outbox: %v", err)
+			}
+		}
+	}
+}
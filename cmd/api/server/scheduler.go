@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/castiglionimax/process-csv/pkg/cron"
+)
+
+const defaultSummarySchedule = "0 8 1 * *"
+
+type (
+	summaryJob interface {
+		SendScheduledSummaries(ctx context.Context, now time.Time) error
+	}
+
+	summaryScheduler struct {
+		schedule cron.Schedule
+		job      summaryJob
+	}
+)
+
+func newSummaryScheduler(job summaryJob) *summaryScheduler {
+	spec := os.Getenv("SUMMARY_SCHEDULE")
+	if spec == "" {
+		spec = defaultSummarySchedule
+	}
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		log.Fatalf("summary scheduler: %v", err)
+	}
+	return &summaryScheduler{schedule: schedule, job: job}
+}
+
+func (s summaryScheduler) Run() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	for {
+		next := s.schedule.Next(time.Now().UTC())
+		if next.IsZero() {
+			log.Printf("summary scheduler: schedule never fires")
+			return
+		}
+		log.Printf("summary scheduler: next run at %s", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case sig := <-signals:
+			timer.Stop()
+			log.Printf("summary scheduler: stopping on %v", sig)
+			return
+		case <-timer.C:
+			if err := s.job.SendScheduledSummaries(context.Background(), next); err != nil {
+				log.Printf("summary scheduler: %v", err)
+			}
+		}
+	}
+}
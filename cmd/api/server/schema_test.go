@@ -0,0 +1,241 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// specDocument is the embedded OpenAPI document, read as plain JSON so the
+// tests need nothing besides the standard library.
+type specDocument map[string]any
+
+func loadSpec() (specDocument, error) {
+	var spec specDocument
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// operation returns the operation of method on the path template.
+func (d specDocument) operation(method, path string) (map[string]any, bool) {
+	paths, _ := d["paths"].(map[string]any)
+	item, _ := paths[path].(map[string]any)
+	operation, ok := item[strings.ToLower(method)].(map[string]any)
+	return operation, ok
+}
+
+// operations lists the documented operations as "METHOD /path".
+func (d specDocument) operations() []string {
+	var operations []string
+	paths, _ := d["paths"].(map[string]any)
+	for path, item := range paths {
+		for method := range item.(map[string]any) {
+			if method != "parameters" {
+				operations = append(operations, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// resolve follows the $ref of node, if any, within the document.
+func (d specDocument) resolve(node map[string]any) (map[string]any, error) {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node, nil
+		}
+		var current any = map[string]any(d)
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			object, _ := current.(map[string]any)
+			current = object[part]
+		}
+		resolved, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolved $ref %s", ref)
+		}
+		node = resolved
+	}
+}
+
+// validate returns the ways value does not conform to schema, each one
+// prefixed with the location of the value. Objects may only carry the
+// properties their schema declares, so a field added to a response must be
+// added to the spec too.
+func (d specDocument) validate(at string, schema map[string]any, value any) []string {
+	return d.check(at, schema, value, false)
+}
+
+// check validates value against schema, open skips the check of undeclared
+// properties for the branches of an allOf.
+func (d specDocument) check(at string, schema map[string]any, value any, open bool) []string {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return []string{at + ": " + err.Error()}
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+	}
+
+	var problems []string
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, branch := range all {
+			problems = append(problems, d.check(at, branch.(map[string]any), value, true)...)
+		}
+		if object, ok := value.(map[string]any); ok && !open {
+			problems = append(problems, d.undeclared(at, schema, object)...)
+		}
+		return problems
+	}
+	if one, ok := schema["oneOf"].([]any); ok {
+		matched := 0
+		for _, branch := range one {
+			if len(d.check(at, branch.(map[string]any), value, open)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			problems = append(problems, fmt.Sprintf("%s: matches %d schemas of oneOf, want 1", at, matched))
+		}
+		return problems
+	}
+
+	if kind, ok := schema["type"].(string); ok && !hasType(kind, value) {
+		return []string{fmt.Sprintf("%s: %s is not of type %s", at, describe(value), kind)}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !contains(enum, value) {
+		problems = append(problems, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+	}
+	if format, _ := schema["format"].(string); format == "date-time" {
+		if text, ok := value.(string); ok {
+			if _, err := time.Parse(time.RFC3339, text); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %q is not a date-time", at, text))
+			}
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := value[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required %s", at, name))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, property := range properties {
+			if field, ok := value[name]; ok {
+				problems = append(problems, d.check(at+"."+name, property.(map[string]any), field, false)...)
+			}
+		}
+		if !open {
+			problems = append(problems, d.undeclared(at, schema, value)...)
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				problems = append(problems, d.check(fmt.Sprintf("%s[%d]", at, i), items, item, false)...)
+			}
+		}
+	}
+	return problems
+}
+
+// undeclared reports the properties of value missing from schema. Schemas
+// without properties, like a free-form object, accept any.
+func (d specDocument) undeclared(at string, schema map[string]any, value map[string]any) []string {
+	declared := d.properties(schema)
+	if declared == nil {
+		return nil
+	}
+	var problems []string
+	for name := range value {
+		if !declared[name] {
+			problems = append(problems, fmt.Sprintf("%s: %s is not in the spec", at, name))
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// properties collects the properties declared by schema and its allOf
+// branches, nil when it declares none.
+func (d specDocument) properties(schema map[string]any) map[string]bool {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return nil
+	}
+	var declared map[string]bool
+	add := func(names map[string]bool) {
+		if names == nil {
+			return
+		}
+		if declared == nil {
+			declared = make(map[string]bool)
+		}
+		for name := range names {
+			declared[name] = true
+		}
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		names := make(map[string]bool)
+		for name := range properties {
+			names[name] = true
+		}
+		add(names)
+	}
+	all, _ := schema["allOf"].([]any)
+	for _, branch := range all {
+		add(d.properties(branch.(map[string]any)))
+	}
+	return declared
+}
+
+func hasType(kind string, value any) bool {
+	switch kind {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == float64(int64(number))
+	default:
+		return false
+	}
+}
+
+func contains(enum []any, value any) bool {
+	for _, option := range enum {
+		if reflect.DeepEqual(option, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func describe(value any) string {
+	if value == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%T %v", value, value)
+}
@@ -16,12 +16,17 @@ const defaultPort = ":8080"
 func StartApplication() {
 	route := chi.NewRouter()
 	route.Use(middleware.Timeout(60 * time.Second))
-	mapping := newMapping()
+	// one service, and so one set of clients, shared by the routes and the
+	// background jobs
+	srv := resolverService()
+	mapping := newMapping(srv)
 	mapping.mapUrlsToControllers(route)
 	serverport := os.Getenv("PORT")
 
 	go newConsumerEvent(resolverQueueConsumer("account")).HandlerAccount()
 	go newConsumerEvent(resolverQueueConsumer("summary")).HandlerSummary()
+	go newSummaryScheduler(srv).Run()
+	go newOutboxSender(srv).Run()
 
 	if serverport == "" {
 		serverport = defaultPort
@@ -1,35 +1,78 @@
 package server
 
 import (
+	_ "embed"
 	"github.com/castiglionimax/process-csv/internal/controller"
+	"github.com/castiglionimax/process-csv/internal/service"
 	"net/http"
 
 	"github.com/go-chi/chi"
 )
 
+// openAPISpec documents the routes mapped below, keep them in sync.
+//
+//go:embed openapi/openapi.json
+var openAPISpec []byte
+
 type mapping struct {
 	controller controller.Controller
 }
 
-func newMapping() *mapping {
+func newMapping(srv *service.Service) *mapping {
 	return &mapping{
-		controller: resolveController(),
+		controller: resolveController(srv),
 	}
 }
 
 func (m mapping) mapUrlsToControllers(route *chi.Mux) {
 	route.Get("/ping", alive())
 
+	route.Get("/openapi.json", openAPI())
+
 	route.Post("/accounts", m.controller.CreateAccount)
 
+	route.Get("/accounts", m.controller.ListAccounts)
+
+	route.Get("/accounts/{id}", m.controller.GetAccount)
+
+	route.Put("/accounts/{id}/preferences", m.controller.UpdatePreferences)
+
+	route.Put("/accounts/{id}/policy", m.controller.UpdatePolicy)
+
+	route.Get("/accounts/{id}/balance", m.controller.GetBalance)
+
+	route.Get("/accounts/{id}/summaries", m.controller.GetSummaries)
+
+	route.Get("/accounts/{id}/transactions", m.controller.GetTransactions)
+
+	route.Get("/accounts/{id}/flags", m.controller.GetFlags)
+
 	route.Post("/csv/upload", m.controller.UploadHandler)
 
 	route.Post("/csv", m.controller.CreateCsv)
 
 	route.Post("/csv/process", m.controller.ProcessFiles)
 
+	route.Post("/transactions/{eventId}/reverse", m.controller.ReverseTransaction)
+
+	route.Post("/transfers", m.controller.CreateTransfer)
+
+	route.Get("/accounts/{id}/summary", m.controller.ExportSummary)
+
 	route.Post("/accounts/{id}/summary/email", m.controller.AccountSummary)
 
+	route.Get("/notifications/{id}", m.controller.GetNotification)
+
+	route.Post("/categories/rules", m.controller.CreateCategoryRule)
+
+	route.Get("/categories/rules", m.controller.ListCategoryRules)
+
+	route.Get("/categories/rules/{ruleId}", m.controller.GetCategoryRule)
+
+	route.Put("/categories/rules/{ruleId}", m.controller.UpdateCategoryRule)
+
+	route.Delete("/categories/rules/{ruleId}", m.controller.DeleteCategoryRule)
+
 }
 
 func alive() func(w http.ResponseWriter, r *http.Request) {
@@ -37,3 +80,10 @@ func alive() func(w http.ResponseWriter, r *http.Request) {
 		_, _ = w.Write([]byte("pong"))
 	}
 }
+
+func openAPI() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPISpec)
+	}
+}
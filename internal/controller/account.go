@@ -0,0 +1,312 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+const dateLayout = "2006-01-02"
+
+func (c Controller) GetAccount(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	account, err := c.service.GetAccount(r.Context(), domain.AccountID(accountID))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, account)
+}
+
+// ListAccounts pages through the accounts, optionally filtered by email,
+// beginning of the name and status.
+func (c Controller) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.AccountFilter{
+		Email:  query.Get("email"),
+		Name:   query.Get("name"),
+		Status: domain.AccountStatus(query.Get("status")),
+		Cursor: query.Get("cursor"),
+	}
+	switch filter.Status {
+	case "", domain.AccountActive, domain.AccountInactive:
+	default:
+		writeError(w, pkgError.ErrInvalidParam.WithMessage("bad status"))
+		return
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 {
+			writeError(w, pkgError.ErrInvalidParam.WithMessage("bad limit"))
+			return
+		}
+	}
+
+	page, err := c.service.ListAccounts(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, page)
+}
+
+func (c Controller) GetBalance(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	balance, err := c.service.GetBalance(r.Context(), domain.AccountID(accountID))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, balance)
+}
+
+func (c Controller) GetSummaries(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	startDate, endDate, err := periodRange(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	granularity, err := summaryGranularity(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	summaries, err := c.service.GetSummaries(r.Context(), domain.AccountID(accountID), granularity, startDate, endDate)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, summaries)
+}
+
+// GetFlags lists the transactions of the account flagged while ingested,
+// within the same range as the summaries.
+func (c Controller) GetFlags(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	startDate, endDate, err := periodRange(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	flags, err := c.service.GetFlags(r.Context(), domain.AccountID(accountID), startDate, endDate)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, flags)
+}
+
+func (c Controller) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	startDate, endDate, err := periodRange(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	filter := domain.TransactionFilter{
+		Start:  startDate,
+		End:    endDate,
+		Type:   domain.TransactionType(r.URL.Query().Get("type")),
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+	switch filter.Type {
+	case "", domain.TransactionCredit, domain.TransactionDebit, domain.TransactionFee, domain.TransactionRefund, domain.TransactionTransfer:
+	default:
+		writeError(w, pkgError.ErrInvalidParam.WithMessage("bad type"))
+		return
+	}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 {
+			writeError(w, pkgError.ErrInvalidParam.WithMessage("bad limit"))
+			return
+		}
+	}
+
+	page, err := c.service.GetTransactions(r.Context(), domain.AccountID(accountID), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, page)
+}
+
+func (c Controller) ExportSummary(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	startDate, endDate, err := periodRange(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	format := domain.ReportFormat(r.URL.Query().Get("format"))
+	switch format {
+	case "":
+		format = domain.ReportJSON
+	case domain.ReportCSV, domain.ReportJSON, domain.ReportHTML, domain.ReportPDF:
+	default:
+		writeError(w, pkgError.ErrInvalidParam.WithMessage("bad format"))
+		return
+	}
+
+	granularity, err := summaryGranularity(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	report, err := c.service.ExportSummary(r.Context(), domain.AccountID(accountID), granularity, startDate, endDate, format)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", report.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", report.Filename))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(report.Content)
+}
+
+func (c Controller) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	var req domain.Preferences
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		writeError(w, pkgError.ErrInvalidBody.Wrap(err))
+		return
+	}
+	req.AccountID = domain.AccountID(accountID)
+
+	if err := req.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := c.service.UpdatePreferences(r.Context(), req); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// UpdatePolicy sets the overdraft policy checked when the transactions of the
+// account are processed.
+func (c Controller) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "id")
+	if accountID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	var req domain.Policy
+	if err := render.DecodeJSON(r.Body, &req); err != nil {
+		writeError(w, pkgError.ErrInvalidBody.Wrap(err))
+		return
+	}
+	req.AccountID = domain.AccountID(accountID)
+
+	if err := req.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := c.service.UpdatePolicy(r.Context(), req); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// periodRange reads the optional start and end query params, both calendar
+// days read in the timezone of the account. When they are missing the range
+// covers the two previous months up to today.
+func periodRange(r *http.Request) (time.Time, time.Time, error) {
+	var (
+		startDate, endDate time.Time
+		err                error
+	)
+
+	startAt := r.URL.Query().Get("start")
+	if startAt != "" {
+		startDate, err = time.Parse(dateLayout, startAt)
+		if err != nil {
+			return time.Time{}, time.Time{}, pkgError.ErrInvalidParam.WithMessage("bad start date")
+		}
+	} else {
+		startDate = domain.CivilDate(time.Now().UTC().AddDate(0, -2, 0))
+	}
+
+	endAt := r.URL.Query().Get("end")
+	if endAt != "" {
+		endDate, err = time.Parse(dateLayout, endAt)
+		if err != nil {
+			return time.Time{}, time.Time{}, pkgError.ErrInvalidParam.WithMessage("bad end date")
+		}
+	} else {
+		endDate = domain.CivilDate(time.Now().UTC()).AddDate(0, 0, 1)
+	}
+
+	return startDate, endDate, nil
+}
+
+// summaryGranularity reads the optional granularity query param, month by
+// default.
+func summaryGranularity(r *http.Request) (domain.Granularity, error) {
+	granularity := domain.Granularity(r.URL.Query().Get("granularity"))
+	if granularity == "" {
+		return domain.GranularityMonth, nil
+	}
+	if !granularity.Valid() {
+		return "", pkgError.ErrInvalidParam.WithMessage("bad granularity")
+	}
+	return granularity, nil
+}
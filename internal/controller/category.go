@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+func (c Controller) CreateCategoryRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeCategoryRule(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := c.service.CreateCategoryRule(r.Context(), rule)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, created)
+}
+
+func (c Controller) UpdateCategoryRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "ruleId")
+	if id == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+	rule, ok := decodeCategoryRule(w, r)
+	if !ok {
+		return
+	}
+	rule.ID = id
+
+	updated, err := c.service.UpdateCategoryRule(r.Context(), rule)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, updated)
+}
+
+func (c Controller) DeleteCategoryRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "ruleId")
+	if id == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	if err := c.service.DeleteCategoryRule(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c Controller) GetCategoryRule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "ruleId")
+	if id == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	rule, err := c.service.GetCategoryRule(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, rule)
+}
+
+// ListCategoryRules returns every rule, or the ones applied to the account
+// given in account_id in the order they are tried.
+func (c Controller) ListCategoryRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := c.service.ListCategoryRules(r.Context(), domain.AccountID(r.URL.Query().Get("account_id")))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, rules)
+}
+
+func decodeCategoryRule(w http.ResponseWriter, r *http.Request) (domain.CategoryRule, bool) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, pkgError.ErrReadingBody)
+		return domain.CategoryRule{}, false
+	}
+
+	var rule domain.CategoryRule
+	if err = json.Unmarshal(data, &rule); err != nil {
+		writeError(w, pkgError.ErrInvalidBody.Wrap(err))
+		return domain.CategoryRule{}, false
+	}
+	rule.Category = strings.TrimSpace(rule.Category)
+	if rule.Match == "" {
+		rule.Match = domain.RuleMatchContains
+	}
+	if err = rule.Validate(); err != nil {
+		writeError(w, err)
+		return domain.CategoryRule{}, false
+	}
+	return rule, true
+}
@@ -10,203 +10,358 @@ import (
 	"github.com/go-chi/render"
 	"io"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/castiglionimax/process-csv/internal/domain"
 	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+	"github.com/castiglionimax/process-csv/pkg/ingest"
 )
 
 type (
 	Service interface {
 		CreateAccount(ctx context.Context, account domain.Account) (domain.AccountID, error)
 		SaveTransactions(ctx context.Context, transactions []domain.Transaction) error
-		ProcessFiles(ctx context.Context) error
-
-		SendEmail(ctx context.Context, accountID domain.AccountID, start, end time.Time) error
+		ProcessFiles(ctx context.Context) (domain.ProcessingReport, error)
+		ReverseTransaction(ctx context.Context, eventID, reason string) (string, error)
+		CreateTransfer(ctx context.Context, transfer domain.Transfer) (string, error)
+
+		SendSummary(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) ([]string, error)
+		UpdatePreferences(ctx context.Context, preferences domain.Preferences) error
+		UpdatePolicy(ctx context.Context, policy domain.Policy) error
+		GetFlags(ctx context.Context, accountID domain.AccountID, start, end time.Time) ([]domain.Flag, error)
+		GetNotification(ctx context.Context, id string) (domain.Notification, error)
+
+		GetAccount(ctx context.Context, accountID domain.AccountID) (domain.Account, error)
+		ListAccounts(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error)
+		GetBalance(ctx context.Context, accountID domain.AccountID) (domain.Balance, error)
+		GetSummaries(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) ([]domain.Summary, error)
+		GetTransactions(ctx context.Context, accountID domain.AccountID, filter domain.TransactionFilter) (domain.TransactionPage, error)
+		ExportSummary(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time, format domain.ReportFormat) (domain.Report, error)
+
+		CreateCategoryRule(ctx context.Context, rule domain.CategoryRule) (domain.CategoryRule, error)
+		UpdateCategoryRule(ctx context.Context, rule domain.CategoryRule) (domain.CategoryRule, error)
+		DeleteCategoryRule(ctx context.Context, id string) error
+		GetCategoryRule(ctx context.Context, id string) (domain.CategoryRule, error)
+		ListCategoryRules(ctx context.Context, accountID domain.AccountID) ([]domain.CategoryRule, error)
 	}
 
 	Controller struct {
-		service Service
+		service  Service
+		profiles ingest.Profiles
 	}
 )
 
-func NewController(service Service) (*Controller, error) {
+func NewController(service Service, profiles ingest.Profiles) (*Controller, error) {
 	if service == nil {
 		return nil, errors.New("service should not be nil")
 	}
+	if profiles == nil {
+		profiles = ingest.DefaultProfiles()
+	}
 	return &Controller{
-		service: service,
+		service:  service,
+		profiles: profiles,
 	}, nil
 }
 
 func (c Controller) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, pkgError.ErrReadingBody.Error(), http.StatusBadRequest)
+		writeError(w, pkgError.ErrReadingBody)
 		return
 	}
 
 	var req domain.Account
 
 	if err = json.Unmarshal(data, &req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, pkgError.ErrInvalidBody.Wrap(err))
+		return
+	}
+
+	if err = req.Validate(); err != nil {
+		writeError(w, err)
 		return
 	}
 
 	account, err := c.service.CreateAccount(r.Context(), req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
+	render.Status(r, http.StatusCreated)
 	render.JSON(w, r, account)
-	w.WriteHeader(http.StatusCreated)
 }
 
 func (c Controller) AccountSummary(w http.ResponseWriter, r *http.Request) {
 	accountID := chi.URLParam(r, "id")
 	if accountID == "" {
-		http.Error(w, "id null", http.StatusBadRequest)
+		writeError(w, pkgError.ErrMissingID)
 		return
 	}
-	var (
-		startDate, endDate time.Time
-		err                error
-	)
-
-	startAt := r.URL.Query().Get("start")
-	if startAt != "" {
-		startDate, err = time.Parse("2006-01-02", startAt)
-		if err != nil {
-			http.Error(w, "bad start date", http.StatusBadRequest)
-			return
-		}
-	} else {
-		startDate = time.Now().UTC().AddDate(0, -2, 0)
+	startDate, endDate, err := periodRange(r)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 
-	end := r.URL.Query().Get("end")
-	if end == "" {
-		endDate, err = time.Parse("2006-01-02", startAt)
-		if err != nil {
-			http.Error(w, "bad start date", http.StatusBadRequest)
-			return
-		}
-	} else {
-		endDate = time.Now().UTC()
+	granularity, err := summaryGranularity(r)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
 
-	if err := c.service.SendEmail(r.Context(), domain.AccountID(accountID), startDate, endDate); err != nil {
-		if errors.As(err, &pkgError.HandlerError{}) {
-			http.Error(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	notificationIDs, err := c.service.SendSummary(r.Context(), domain.AccountID(accountID), granularity, startDate, endDate)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]any{"notification_id": notificationIDs[0], "notification_ids": notificationIDs})
 }
 
+// UploadHandler reads a CSV of account_id, date and amount, optionally
+// followed by type, description, merchant, category and reference. The
+// optional profile query param names the import profile describing its
+// formats.
 func (c Controller) UploadHandler(w http.ResponseWriter, r *http.Request) {
-	err := r.ParseMultipartForm(10 << 20)
+	profile, err := c.profiles.Get(r.URL.Query().Get("profile"))
+	if err != nil {
+		writeError(w, pkgError.ErrInvalidProfile.Wrap(err))
+		return
+	}
+
+	err = r.ParseMultipartForm(10 << 20)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, pkgError.ErrInvalidFile.Wrap(err))
 		return
 	}
 
 	file, _, err := r.FormFile("csv")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, pkgError.ErrInvalidFile.Wrap(err))
 		return
 	}
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	reader.Comma = profile.Comma()
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
 
 	var transactions []domain.Transaction
 
-	for {
+	for first := true; ; first = false {
 		line, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, pkgError.ErrInvalidFile.Wrap(err))
 			return
 		}
+		if (first && profile.SkipHeader) || len(line) < 3 {
+			continue
+		}
 
-		parsedTimestamp, err := strconv.ParseInt(line[1], 10, 64)
+		parsedDate, err := profile.ParseTime(line[1])
 		if err != nil {
 			continue
 		}
 
-		parsedAmount, err := strconv.ParseFloat(line[2], 64)
+		parsedAmount, err := profile.ParseAmount(line[2])
 		if err != nil || parsedAmount == 0 {
 			continue
 		}
 
-		gotten := domain.Transaction{
-			AccountID: domain.AccountID(line[0]),
-			Date:      time.Unix(parsedTimestamp, 0).UTC(),
-			Amount:    parsedAmount,
+		gotten, err := domain.Transaction{
+			AccountID:   domain.AccountID(line[0]),
+			Date:        parsedDate,
+			Amount:      parsedAmount,
+			Type:        domain.TransactionType(strings.ToLower(column(line, 3))),
+			Description: column(line, 4),
+			Merchant:    column(line, 5),
+			Category:    column(line, 6),
+			Reference:   column(line, 7),
+		}.Normalize()
+		if err != nil {
+			continue
 		}
 
 		transactions = append(transactions, gotten)
 	}
 
 	if err = c.service.SaveTransactions(r.Context(), transactions); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// ReverseTransaction undoes a processed transaction given the ID of its
+// credit or debit event. The body may carry the reason of the reversal.
+func (c Controller) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "eventId")
+	if eventID == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, pkgError.ErrReadingBody)
+		return
+	}
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &req); err != nil {
+			writeError(w, pkgError.ErrInvalidBody.Wrap(err))
+			return
+		}
+	}
+
+	reversalID, err := c.service.ReverseTransaction(r.Context(), eventID, strings.TrimSpace(req.Reason))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.Status(r, http.StatusAccepted)
+	render.JSON(w, r, map[string]string{"event_id": reversalID})
+}
+
+// ProcessFiles stores the staged transactions and answers with the processing
+// report.
 func (c Controller) ProcessFiles(w http.ResponseWriter, r *http.Request) {
-	if err := c.service.ProcessFiles(r.Context()); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	report, err := c.service.ProcessFiles(r.Context())
+	if err != nil {
+		writeError(w, err)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
+	render.JSON(w, r, report)
 }
 
+// CreateCsv takes the transactions as JSON. timestamp and amount may be JSON
+// numbers or strings, strings are read with the import profile. type,
+// description, merchant, category and reference are optional.
 func (c Controller) CreateCsv(w http.ResponseWriter, r *http.Request) {
+	profile, err := c.profiles.Get(r.URL.Query().Get("profile"))
+	if err != nil {
+		writeError(w, pkgError.ErrInvalidProfile.Wrap(err))
+		return
+	}
+
 	data, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, pkgError.ErrReadingBody.Error(), http.StatusBadRequest)
+		writeError(w, pkgError.ErrReadingBody)
 		return
 	}
 
 	var req []struct {
-		AccountId string `json:"account_id"`
-		Timestamp int64  `json:"timestamp"`
-		Amount    string `json:"amount"`
+		AccountId   string          `json:"account_id"`
+		Timestamp   json.RawMessage `json:"timestamp"`
+		Amount      json.RawMessage `json:"amount"`
+		Type        string          `json:"type"`
+		Description string          `json:"description"`
+		Merchant    string          `json:"merchant"`
+		Category    string          `json:"category"`
+		Reference   string          `json:"reference"`
 	}
 
 	if err = json.Unmarshal(data, &req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, pkgError.ErrInvalidBody.Wrap(err))
 		return
 	}
-	var transactions []domain.Transaction
+	var (
+		transactions []domain.Transaction
+		errs         pkgError.ValidationError
+	)
 
-	for _, object := range req {
-		parsedAmount, err := strconv.ParseFloat(object.Amount, 64)
-		if err != nil || parsedAmount == 0 {
-			fmt.Printf("error reading line %v", err)
-			continue
+	for i, object := range req {
+		field := func(name string) string { return fmt.Sprintf("[%d].%s", i, name) }
+		valid := true
+
+		if strings.TrimSpace(object.AccountId) == "" {
+			errs.Add(field("account_id"), "is required")
+			valid = false
+		}
+
+		parsedDate, err := parseTimestamp(profile, object.Timestamp)
+		if err != nil {
+			errs.Add(field("timestamp"), err.Error())
+			valid = false
 		}
 
-		gotten := domain.Transaction{
-			AccountID: domain.AccountID(object.AccountId),
-			Date:      time.Unix(object.Timestamp, 0),
-			Amount:    parsedAmount,
+		parsedAmount, err := parseAmount(profile, object.Amount)
+		switch {
+		case err != nil:
+			errs.Add(field("amount"), err.Error())
+			valid = false
+		case parsedAmount == 0:
+			errs.Add(field("amount"), "must not be zero")
+			valid = false
+		}
+
+		gotten, err := domain.Transaction{
+			AccountID:   domain.AccountID(object.AccountId),
+			Date:        parsedDate,
+			Amount:      parsedAmount,
+			Type:        domain.TransactionType(strings.ToLower(strings.TrimSpace(object.Type))),
+			Description: strings.TrimSpace(object.Description),
+			Merchant:    strings.TrimSpace(object.Merchant),
+			Category:    strings.TrimSpace(object.Category),
+			Reference:   strings.TrimSpace(object.Reference),
+		}.Normalize()
+		if err != nil {
+			errs.Add(field("type"), err.Error())
+			valid = false
+		}
+		if valid {
+			transactions = append(transactions, gotten)
 		}
-		transactions = append(transactions, gotten)
+	}
+	if err = errs.Err(); err != nil {
+		writeError(w, err)
+		return
 	}
 
 	if err = c.service.SaveTransactions(r.Context(), transactions); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// column returns the trimmed field i of a CSV line, empty when missing.
+func column(line []string, i int) string {
+	if i >= len(line) {
+		return ""
+	}
+	return strings.TrimSpace(line[i])
+}
+
+func parseTimestamp(profile ingest.Profile, raw json.RawMessage) (time.Time, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return profile.ParseTime(text)
+	}
+	var epoch int64
+	if err := json.Unmarshal(raw, &epoch); err != nil {
+		return time.Time{}, fmt.Errorf("bad timestamp %s", raw)
+	}
+	return ingest.Epoch(epoch), nil
+}
+
+func parseAmount(profile ingest.Profile, raw json.RawMessage) (float64, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return profile.ParseAmount(text)
+	}
+	var amount float64
+	if err := json.Unmarshal(raw, &amount); err != nil {
+		return 0, fmt.Errorf("bad amount %s", raw)
+	}
+	return amount, nil
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+const problemContentType = "application/problem+json"
+
+// problem is the body of every error response, an RFC 7807 problem details
+// object. Code is the stable code of the catalog error.
+type problem struct {
+	Type   string                `json:"type"`
+	Title  string                `json:"title"`
+	Status int                   `json:"status"`
+	Detail string                `json:"detail,omitempty"`
+	Code   string                `json:"code"`
+	Errors []pkgError.FieldError `json:"errors,omitempty"`
+}
+
+// statuses maps every kind of the catalog to the status it is answered with.
+var statuses = map[pkgError.Kind]int{
+	pkgError.KindBadRequest:  http.StatusBadRequest,
+	pkgError.KindValidation:  http.StatusUnprocessableEntity,
+	pkgError.KindNotFound:    http.StatusNotFound,
+	pkgError.KindConflict:    http.StatusConflict,
+	pkgError.KindUnavailable: http.StatusServiceUnavailable,
+	pkgError.KindInternal:    http.StatusInternalServerError,
+}
+
+// writeError answers with the problem of err. Errors outside the catalog
+// are answered as internal errors, or as unavailable dependencies when they
+// come from the network, and their messages only reach the logs.
+func writeError(w http.ResponseWriter, err error) {
+	var (
+		catalog    *pkgError.Error
+		validation pkgError.ValidationError
+		body       problem
+	)
+	switch {
+	case errors.As(err, &validation):
+		catalog = pkgError.ErrValidation
+		body.Errors = validation.Fields
+	case errors.As(err, &catalog):
+	case unavailable(err):
+		catalog = pkgError.ErrUnavailable.Wrap(err)
+	default:
+		catalog = pkgError.ErrInternal.Wrap(err)
+	}
+
+	status, ok := statuses[catalog.Kind]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	body.Type = "about:blank"
+	body.Title = http.StatusText(status)
+	body.Status = status
+	body.Code = catalog.Code
+	body.Detail = catalog.Message
+	if catalog.Kind == pkgError.KindBadRequest {
+		// the cause of a bad request tells what is wrong in it
+		body.Detail = catalog.Error()
+	}
+	if status >= http.StatusInternalServerError {
+		log.Printf("%s: %v", catalog.Code, err)
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// unavailable tells whether err comes from a dependency that could not be
+// reached in time.
+func unavailable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) ||
+		errors.As(err, &netErr)
+}
@@ -10,8 +10,12 @@ import (
 type (
 	eventService interface {
 		CreateAccount(ctx context.Context, account domain.Account) error
+		UpdatePreferences(ctx context.Context, preferences domain.Preferences) error
+		UpdatePolicy(ctx context.Context, policy domain.Policy) error
 		RegisterTransaction(ctx context.Context, tx domain.Transaction) error
-		RegisterSummary(ctx context.Context, tx domain.Transaction) error
+		RegisterSummary(ctx context.Context, eventID string, tx domain.Transaction) error
+		ReverseTransaction(ctx context.Context, reversal domain.Reversal) error
+		ReverseSummary(ctx context.Context, reversal domain.Reversal) error
 	}
 
 	EventHandler struct {
@@ -33,6 +37,26 @@ func (h EventHandler) SaveAccount(ctx context.Context, body []byte) error {
 	return h.eventService.CreateAccount(ctx, acc)
 }
 
+func (h EventHandler) UpdatePreferences(ctx context.Context, body []byte) error {
+	var preferences domain.Preferences
+
+	if err := json.Unmarshal(body, &preferences); err != nil {
+		return err
+	}
+
+	return h.eventService.UpdatePreferences(ctx, preferences)
+}
+
+func (h EventHandler) UpdatePolicy(ctx context.Context, body []byte) error {
+	var policy domain.Policy
+
+	if err := json.Unmarshal(body, &policy); err != nil {
+		return err
+	}
+
+	return h.eventService.UpdatePolicy(ctx, policy)
+}
+
 func (h EventHandler) RegisterTransaction(ctx context.Context, body []byte) error {
 	var tx domain.Transaction
 
@@ -43,12 +67,32 @@ func (h EventHandler) RegisterTransaction(ctx context.Context, body []byte) erro
 	return h.eventService.RegisterTransaction(ctx, tx)
 }
 
-func (h EventHandler) RegisterSummary(ctx context.Context, body []byte) error {
+func (h EventHandler) RegisterSummary(ctx context.Context, eventID string, body []byte) error {
 	var tx domain.Transaction
 
 	if err := json.Unmarshal(body, &tx); err != nil {
 		return err
 	}
 
-	return h.eventService.RegisterSummary(ctx, tx)
+	return h.eventService.RegisterSummary(ctx, eventID, tx)
+}
+
+func (h EventHandler) ReverseTransaction(ctx context.Context, body []byte) error {
+	var reversal domain.Reversal
+
+	if err := json.Unmarshal(body, &reversal); err != nil {
+		return err
+	}
+
+	return h.eventService.ReverseTransaction(ctx, reversal)
+}
+
+func (h EventHandler) ReverseSummary(ctx context.Context, body []byte) error {
+	var reversal domain.Reversal
+
+	if err := json.Unmarshal(body, &reversal); err != nil {
+		return err
+	}
+
+	return h.eventService.ReverseSummary(ctx, reversal)
 }
@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+func (c Controller) GetNotification(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		writeError(w, pkgError.ErrMissingID)
+		return
+	}
+
+	notification, err := c.service.GetNotification(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.JSON(w, r, notification)
+}
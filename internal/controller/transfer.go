@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/render"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+// CreateTransfer moves a positive amount from one account to another. date is
+// optional, RFC 3339.
+func (c Controller) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, pkgError.ErrReadingBody)
+		return
+	}
+
+	var req domain.Transfer
+	if err = json.Unmarshal(data, &req); err != nil {
+		writeError(w, pkgError.ErrInvalidBody.Wrap(err))
+		return
+	}
+	if err = req.Validate(); err != nil {
+		writeError(w, err)
+		return
+	}
+	req.Description = strings.TrimSpace(req.Description)
+	req.Reference = strings.TrimSpace(req.Reference)
+
+	transferID, err := c.service.CreateTransfer(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, map[string]string{"transfer_id": transferID})
+}
@@ -1,9 +1,259 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"math"
+	"time"
 
-type Transaction struct {
-	AccountID AccountID `json:"account_id"`
-	Date      time.Time `json:"date"`
-	Amount    float64   `json:"amount"`
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+type TransactionType string
+
+type ReportFormat string
+
+const (
+	ReportCSV  ReportFormat = "csv"
+	ReportJSON ReportFormat = "json"
+	ReportHTML ReportFormat = "html"
+	ReportPDF  ReportFormat = "pdf"
+)
+
+const (
+	TransactionCredit   TransactionType = "credit"
+	TransactionDebit    TransactionType = "debit"
+	TransactionFee      TransactionType = "fee"
+	TransactionRefund   TransactionType = "refund"
+	TransactionTransfer TransactionType = "transfer"
+)
+
+type (
+	Transaction struct {
+		AccountID AccountID       `json:"account_id"`
+		Date      time.Time       `json:"date"`
+		Amount    float64         `json:"amount"`
+		Type      TransactionType `json:"type,omitempty"`
+
+		Description string `json:"description,omitempty"`
+		Merchant    string `json:"merchant,omitempty"`
+		Category    string `json:"category,omitempty"`
+		// Reference is the ID given to the transaction by its source. When
+		// set, it is the idempotency key of the transaction.
+		Reference string `json:"reference,omitempty"`
+
+		// TransferID is shared by the two sides of a transfer between
+		// accounts, Counterparty is the account on the other side.
+		TransferID   string    `json:"transfer_id,omitempty"`
+		Counterparty AccountID `json:"counterparty,omitempty"`
+	}
+
+	// Transfer moves Amount from one account to another.
+	Transfer struct {
+		ID          string    `json:"transfer_id"`
+		From        AccountID `json:"from_account_id"`
+		To          AccountID `json:"to_account_id"`
+		Amount      float64   `json:"amount"`
+		Date        time.Time `json:"date"`
+		Description string    `json:"description,omitempty"`
+		Reference   string    `json:"reference,omitempty"`
+	}
+
+	Balance struct {
+		AccountID   AccountID `json:"account_id"`
+		Amount      float64   `json:"amount"`
+		LastUpdated time.Time `json:"last_updated"`
+	}
+
+	TransactionEvent struct {
+		EventID     string      `json:"event_id"`
+		EventType   string      `json:"event_type"`
+		AggregateID AccountID   `json:"aggregate_id"`
+		Time        time.Time   `json:"time"`
+		Data        Transaction `json:"data"`
+		// ReversedBy is the ID of the event reversing the transaction, if any.
+		ReversedBy string `json:"reversed_by,omitempty"`
+	}
+
+	// Violation is a transaction breaking a policy of its account, or caught
+	// by an anomaly rule, while being ingested. Policy names the policy or the
+	// rule. Balance is the balance of the account before it, when known.
+	Violation struct {
+		Transaction Transaction `json:"transaction"`
+		Policy      string      `json:"policy"`
+		Reason      string      `json:"reason"`
+		Balance     float64     `json:"balance"`
+	}
+
+	// Flag is a transaction_flagged event.
+	Flag struct {
+		EventID string    `json:"event_id"`
+		Time    time.Time `json:"time"`
+		Violation
+	}
+
+	// AmountStats describes the sizes of the transactions stored for an
+	// account, without sign.
+	AmountStats struct {
+		Count  int     `json:"count"`
+		Mean   float64 `json:"mean"`
+		StdDev float64 `json:"std_dev"`
+	}
+
+	// ProcessingReport tells what became of the staged transactions: how many
+	// were received and stored, and the ones rejected or flagged by the
+	// policies of their accounts.
+	ProcessingReport struct {
+		Received int         `json:"received"`
+		Accepted int         `json:"accepted"`
+		Rejected []Violation `json:"rejected"`
+		Flagged  []Violation `json:"flagged"`
+	}
+
+	// Reversal undoes the transaction of the credit or debit event EventID,
+	// Transaction is a copy of it.
+	Reversal struct {
+		EventID     string      `json:"event_id"`
+		Transaction Transaction `json:"transaction"`
+		Reason      string      `json:"reason,omitempty"`
+	}
+
+	TransactionFilter struct {
+		Start, End time.Time
+		Type       TransactionType
+		Cursor     string
+		Limit      int
+	}
+
+	TransactionPage struct {
+		Items      []TransactionEvent `json:"items"`
+		NextCursor string             `json:"next_cursor,omitempty"`
+	}
+
+	// AccountFilter narrows the accounts listed: Email matches the whole
+	// address, Name its beginning.
+	AccountFilter struct {
+		Email  string
+		Name   string
+		Status AccountStatus
+		Cursor string
+		Limit  int
+	}
+
+	AccountPage struct {
+		Items      []Account `json:"items"`
+		NextCursor string    `json:"next_cursor,omitempty"`
+	}
+
+	Report struct {
+		Filename    string
+		ContentType string
+		Content     []byte
+	}
+
+	Summary struct {
+		Period         string    `json:"period"`
+		Credit         float64   `json:"credit"`
+		CreditQty      int       `json:"credit_qty"`
+		Debit          float64   `json:"debit"`
+		DebitQty       int       `json:"debit_qty"`
+		TransferIn     float64   `json:"transfer_in"`
+		TransferInQty  int       `json:"transfer_in_qty"`
+		TransferOut    float64   `json:"transfer_out"`
+		TransferOutQty int       `json:"transfer_out_qty"`
+		MinCredit      float64   `json:"min_credit"`
+		MaxCredit      float64   `json:"max_credit"`
+		MinDebit       float64   `json:"min_debit"`
+		MaxDebit       float64   `json:"max_debit"`
+		OpeningBalance float64   `json:"opening_balance"`
+		ClosingBalance float64   `json:"closing_balance"`
+		LastUpdated    time.Time `json:"last_updated"`
+	}
+
+	// Statistics aggregates the movements of one period or of a whole range.
+	// Debit and its average are negative like the transactions they come
+	// from; the min and max fields are transaction sizes and carry no sign.
+	// Transfers between accounts are not counted as credits or debits, only
+	// in the transfer fields, the movements and the net flow.
+	Statistics struct {
+		Movements      int     `json:"movements"`
+		Credit         float64 `json:"credit"`
+		CreditQty      int     `json:"credit_qty"`
+		Debit          float64 `json:"debit"`
+		DebitQty       int     `json:"debit_qty"`
+		TransferIn     float64 `json:"transfer_in"`
+		TransferInQty  int     `json:"transfer_in_qty"`
+		TransferOut    float64 `json:"transfer_out"`
+		TransferOutQty int     `json:"transfer_out_qty"`
+		AverageCredit  float64 `json:"average_credit"`
+		AverageDebit   float64 `json:"average_debit"`
+		MinCredit      float64 `json:"min_credit"`
+		MaxCredit      float64 `json:"max_credit"`
+		MinDebit       float64 `json:"min_debit"`
+		MaxDebit       float64 `json:"max_debit"`
+		NetFlow        float64 `json:"net_flow"`
+	}
+
+	PeriodStatistics struct {
+		Period         string  `json:"period"`
+		OpeningBalance float64 `json:"opening_balance"`
+		ClosingBalance float64 `json:"closing_balance"`
+		Statistics
+	}
+
+	// SummaryReport is the content of a balance summary: the statistics of
+	// every period in the range, of the range as a whole, and the balance of
+	// the account before and after it.
+	SummaryReport struct {
+		AccountID      AccountID          `json:"account_id"`
+		Granularity    Granularity        `json:"granularity"`
+		Balance        float64            `json:"balance"`
+		OpeningBalance float64            `json:"opening_balance"`
+		ClosingBalance float64            `json:"closing_balance"`
+		Periods        []PeriodStatistics `json:"periods"`
+		Totals         Statistics         `json:"totals"`
+		// Categories adds up the movements of the range by category, the
+		// largest spending first.
+		Categories []CategorySpending `json:"categories,omitempty"`
+		// Flags are the movements of the range flagged while ingested.
+		Flags []Flag `json:"flags,omitempty"`
+	}
+)
+
+// Normalize sets the type of the transaction and the sign of its amount to
+// agree: without a type the sign decides between credit and debit, debits and
+// fees are always negative, credits and refunds positive, and transfers keep
+// their sign.
+func (t Transaction) Normalize() (Transaction, error) {
+	switch t.Type {
+	case "":
+		t.Type = TransactionCredit
+		if t.Amount < 0 {
+			t.Type = TransactionDebit
+		}
+	case TransactionDebit, TransactionFee:
+		t.Amount = -math.Abs(t.Amount)
+	case TransactionCredit, TransactionRefund:
+		t.Amount = math.Abs(t.Amount)
+	case TransactionTransfer:
+	default:
+		return t, fmt.Errorf("unknown transaction type %q", t.Type)
+	}
+	return t, nil
+}
+
+// Validate checks a transfer about to be registered.
+func (t Transfer) Validate() error {
+	var errs pkgError.ValidationError
+	if t.From == "" {
+		errs.Add("from_account_id", "is required")
+	}
+	if t.To == "" {
+		errs.Add("to_account_id", "is required")
+	} else if t.To == t.From {
+		errs.Add("to_account_id", "must differ from from_account_id")
+	}
+	if !(t.Amount > 0) {
+		errs.Add("amount", "must be positive")
+	}
+	return errs.Err()
 }
@@ -0,0 +1,24 @@
+package domain
+
+import "testing"
+
+func TestTransferValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		transfer Transfer
+		fields   string
+	}{
+		{name: "valid", transfer: Transfer{From: "a", To: "b", Amount: 10}},
+		{name: "missing accounts", transfer: Transfer{Amount: 10}, fields: "from_account_id,to_account_id"},
+		{name: "same account", transfer: Transfer{From: "a", To: "a", Amount: 10}, fields: "to_account_id"},
+		{name: "zero amount", transfer: Transfer{From: "a", To: "b"}, fields: "amount"},
+		{name: "negative amount", transfer: Transfer{From: "a", To: "b", Amount: -1}, fields: "amount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if fields := refusedFields(t, tt.transfer.Validate()); fields != tt.fields {
+				t.Fatalf("Validate() refused %q, want %q", fields, tt.fields)
+			}
+		})
+	}
+}
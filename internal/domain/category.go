@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+type (
+	RuleField string
+	RuleMatch string
+)
+
+const (
+	RuleFieldDescription RuleField = "description"
+	RuleFieldMerchant    RuleField = "merchant"
+
+	RuleMatchContains RuleMatch = "contains"
+	RuleMatchRegex    RuleMatch = "regex"
+
+	// Uncategorized is the category of transactions no rule matches.
+	Uncategorized = "uncategorized"
+
+	maxCategoryLength = 64
+)
+
+type (
+	// CategoryRule sets Category on the transactions whose Field matches
+	// Pattern. Contains matches ignore case. Rules without an account apply to
+	// every account.
+	CategoryRule struct {
+		ID        string    `json:"id"`
+		AccountID AccountID `json:"account_id,omitempty"`
+		Field     RuleField `json:"field"`
+		Match     RuleMatch `json:"match"`
+		Pattern   string    `json:"pattern"`
+		Category  string    `json:"category"`
+		Priority  int       `json:"priority"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+
+	// CategorySpending adds up the movements of one category, debits are
+	// negative like in Summary.
+	CategorySpending struct {
+		Category  string  `json:"category"`
+		Credit    float64 `json:"credit"`
+		CreditQty int     `json:"credit_qty"`
+		Debit     float64 `json:"debit"`
+		DebitQty  int     `json:"debit_qty"`
+	}
+)
+
+// Validate checks a rule about to be stored.
+func (r CategoryRule) Validate() error {
+	var errs pkgError.ValidationError
+	switch r.Field {
+	case RuleFieldDescription, RuleFieldMerchant:
+	default:
+		errs.Add("field", fmt.Sprintf("unknown field %q", r.Field))
+	}
+
+	switch r.Match {
+	case RuleMatchContains, RuleMatchRegex:
+		if r.Pattern == "" {
+			errs.Add("pattern", "is required")
+		} else if _, err := r.matcher(); err != nil {
+			errs.Add("pattern", err.Error())
+		}
+	default:
+		errs.Add("match", fmt.Sprintf("unknown match %q", r.Match))
+	}
+
+	switch category := strings.TrimSpace(r.Category); {
+	case category == "":
+		errs.Add("category", "is required")
+	case len([]rune(category)) > maxCategoryLength:
+		errs.Add("category", fmt.Sprintf("must be at most %d characters", maxCategoryLength))
+	}
+	return errs.Err()
+}
+
+func (r CategoryRule) matcher() (func(string) bool, error) {
+	switch r.Match {
+	case RuleMatchContains:
+		pattern := strings.ToLower(r.Pattern)
+		return func(value string) bool { return strings.Contains(strings.ToLower(value), pattern) }, nil
+	case RuleMatchRegex:
+		expression, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern: %w", err)
+		}
+		return expression.MatchString, nil
+	default:
+		return nil, fmt.Errorf("unknown match %q", r.Match)
+	}
+}
+
+// Categorize returns the category of the transaction: the one it came with,
+// cut to the longest category stored, else the one of the first rule matching
+// it, else Uncategorized. rules must be sorted by precedence.
+func Categorize(tx Transaction, rules []CategoryRule) string {
+	if category := []rune(strings.TrimSpace(tx.Category)); len(category) > 0 {
+		return string(category[:min(len(category), maxCategoryLength)])
+	}
+	for _, rule := range rules {
+		matches, err := rule.matcher()
+		if err != nil {
+			continue
+		}
+		value := tx.Description
+		if rule.Field == RuleFieldMerchant {
+			value = tx.Merchant
+		}
+		if value != "" && matches(value) {
+			return rule.Category
+		}
+	}
+	return Uncategorized
+}
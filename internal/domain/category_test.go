@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCategoryRuleValidate(t *testing.T) {
+	valid := CategoryRule{Field: RuleFieldMerchant, Match: RuleMatchContains, Pattern: "coffee", Category: "food"}
+	tests := []struct {
+		name   string
+		change func(*CategoryRule)
+		fields string
+	}{
+		{name: "valid", change: func(*CategoryRule) {}},
+		{name: "regex", change: func(r *CategoryRule) { r.Match, r.Pattern = RuleMatchRegex, "^(uber|lyft)" }},
+		{name: "unknown field", change: func(r *CategoryRule) { r.Field = "amount" }, fields: "field"},
+		{name: "unknown match", change: func(r *CategoryRule) { r.Match = "glob" }, fields: "match"},
+		{name: "empty pattern", change: func(r *CategoryRule) { r.Pattern = "" }, fields: "pattern"},
+		{name: "bad regex", change: func(r *CategoryRule) { r.Match, r.Pattern = RuleMatchRegex, "(" }, fields: "pattern"},
+		{name: "blank category", change: func(r *CategoryRule) { r.Category = " " }, fields: "category"},
+		{name: "long category", change: func(r *CategoryRule) { r.Category = strings.Repeat("x", maxCategoryLength+1) }, fields: "category"},
+		{name: "everything", change: func(r *CategoryRule) { *r = CategoryRule{} }, fields: "field,match,category"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := valid
+			tt.change(&rule)
+			if fields := refusedFields(t, rule.Validate()); fields != tt.fields {
+				t.Fatalf("Validate() refused %q, want %q", fields, tt.fields)
+			}
+		})
+	}
+}
@@ -1,14 +1,302 @@
 package domain
 
+import (
+	"fmt"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+	"github.com/castiglionimax/process-csv/pkg/i18n"
+)
+
+type AccountStatus string
+
+const (
+	AccountActive   AccountStatus = "active"
+	AccountInactive AccountStatus = "inactive"
+)
+
+type NotificationStatus string
+
+const (
+	NotificationQueued NotificationStatus = "queued"
+	NotificationSent   NotificationStatus = "sent"
+	NotificationFailed NotificationStatus = "failed"
+)
+
+type OverdraftPolicy string
+
+const (
+	// OverdraftFlag accepts every debit and reports the ones leaving the
+	// balance below zero.
+	OverdraftFlag OverdraftPolicy = "flag"
+	// OverdraftAllow accepts debits down to minus the overdraft limit and
+	// rejects the rest.
+	OverdraftAllow OverdraftPolicy = "allow"
+	// OverdraftReject rejects debits leaving the balance below zero.
+	OverdraftReject OverdraftPolicy = "reject"
+)
+
+type Channel string
+
+const (
+	ChannelEmail   Channel = "email"
+	ChannelWebhook Channel = "webhook"
+	ChannelFile    Channel = "file"
+)
+
 type (
 	AccountID string
 	Account   struct {
-		ID    AccountID `json:"account_id"`
-		Name  string    `json:"name"`
-		Email string    `json:"email"`
+		ID     AccountID     `json:"account_id"`
+		Name   string        `json:"name"`
+		Email  string        `json:"email"`
+		Locale string        `json:"locale"`
+		Status AccountStatus `json:"status"`
+
+		// Timezone is the IANA zone transactions are bucketed in, UTC by
+		// default.
+		Timezone string `json:"timezone"`
+
+		Channels   []Channel `json:"channels"`
+		WebhookURL string    `json:"webhook_url,omitempty"`
+
+		OverdraftPolicy OverdraftPolicy `json:"overdraft_policy"`
+		OverdraftLimit  float64         `json:"overdraft_limit"`
+	}
+
+	// Policy is the overdraft policy of an account.
+	Policy struct {
+		AccountID       AccountID       `json:"account_id"`
+		OverdraftPolicy OverdraftPolicy `json:"overdraft_policy"`
+		OverdraftLimit  float64         `json:"overdraft_limit"`
+	}
+
+	Preferences struct {
+		AccountID  AccountID `json:"account_id"`
+		Channels   []Channel `json:"channels"`
+		WebhookURL string    `json:"webhook_url,omitempty"`
+	}
+
+	// Delivery is a notification ready to be handed to the notifier of its
+	// channel. Payload carries the summary as JSON for machine readable
+	// channels.
+	Delivery struct {
+		ID        string
+		AccountID AccountID
+		Channel   Channel
+		Recipient string
+		Subject   string
+		HTML      string
+		Text      string
+		Payload   []byte
+		Attempts  int
+	}
+
+	Notification struct {
+		ID        string             `json:"id"`
+		AccountID AccountID          `json:"account_id"`
+		Channel   Channel            `json:"channel"`
+		Recipient string             `json:"recipient"`
+		Subject   string             `json:"subject"`
+		Status    NotificationStatus `json:"status"`
+		Attempts  int                `json:"attempts"`
+		LastError string             `json:"last_error,omitempty"`
+		CreatedAt time.Time          `json:"created_at"`
+		UpdatedAt time.Time          `json:"updated_at"`
 	}
 )
 
 func (a AccountID) String() string {
 	return string(a)
 }
+
+// DefaultTimezone is used for accounts created without a timezone.
+const DefaultTimezone = "UTC"
+
+// Location returns the timezone of the account, UTC when it is not set or not
+// known.
+func (a Account) Location() *time.Location {
+	if a.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(a.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+const (
+	maxNameLength   = 255
+	maxEmailLength  = 250
+	maxLocaleLength = 16
+
+	maxWebhookURLLength = 2048
+)
+
+// Validate checks an account about to be created. The ID is assigned on
+// creation, so it must not come with the request.
+func (a Account) Validate() error {
+	var errs pkgError.ValidationError
+	if a.ID != "" {
+		errs.Add("account_id", "must not be set, it is assigned on creation")
+	}
+
+	switch name := strings.TrimSpace(a.Name); {
+	case name == "":
+		errs.Add("name", "is required")
+	case len([]rune(name)) > maxNameLength:
+		errs.Add("name", fmt.Sprintf("must be at most %d characters", maxNameLength))
+	}
+
+	switch email := strings.TrimSpace(a.Email); {
+	case email == "":
+		errs.Add("email", "is required")
+	case len(email) > maxEmailLength:
+		errs.Add("email", fmt.Sprintf("must be at most %d characters", maxEmailLength))
+	case !validEmail(email):
+		errs.Add("email", "is not a valid address")
+	}
+
+	switch {
+	case a.Locale == "":
+	case len(a.Locale) > maxLocaleLength:
+		errs.Add("locale", fmt.Sprintf("must be at most %d characters", maxLocaleLength))
+	case !i18n.Supported(a.Locale):
+		errs.Add("locale", fmt.Sprintf("unsupported locale %q", a.Locale))
+	}
+
+	if a.Timezone != "" {
+		if _, err := time.LoadLocation(a.Timezone); err != nil {
+			errs.Add("timezone", fmt.Sprintf("unknown timezone %q", a.Timezone))
+		}
+	}
+	if a.OverdraftPolicy != "" && !a.OverdraftPolicy.Valid() {
+		errs.Add("overdraft_policy", fmt.Sprintf("unknown overdraft policy %q", a.OverdraftPolicy))
+	}
+	if a.OverdraftLimit < 0 {
+		errs.Add("overdraft_limit", "must not be negative")
+	}
+
+	validateChannels(&errs, a.Channels, a.WebhookURL)
+	return errs.Err()
+}
+
+// Validate checks the notification preferences of an account.
+func (p Preferences) Validate() error {
+	var errs pkgError.ValidationError
+	validateChannels(&errs, p.Channels, p.WebhookURL)
+	return errs.Err()
+}
+
+// validateChannels adds to errs the unknown channels and a missing or bad
+// webhook URL. The URL is checked whenever it is set, it is kept even when
+// the webhook channel is not chosen.
+func validateChannels(errs *pkgError.ValidationError, channels []Channel, webhookURL string) {
+	for _, channel := range channels {
+		switch channel {
+		case ChannelEmail, ChannelFile, ChannelWebhook:
+		default:
+			errs.Add("channels", fmt.Sprintf("unknown channel %q", channel))
+		}
+	}
+
+	switch {
+	case webhookURL == "":
+		for _, channel := range channels {
+			if channel == ChannelWebhook {
+				errs.Add("webhook_url", "is required for the webhook channel")
+				break
+			}
+		}
+	case len(webhookURL) > maxWebhookURLLength:
+		errs.Add("webhook_url", fmt.Sprintf("must be at most %d characters", maxWebhookURLLength))
+	case !ValidWebhookURL(webhookURL):
+		errs.Add("webhook_url", "must be an absolute http or https URL to a public host")
+	}
+}
+
+// ValidWebhookURL accepts absolute http and https URLs with a host and
+// without credentials. A host given as an IP address must be public, host
+// names are checked once resolved, when the webhook is dialed.
+func ValidWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.User != nil || u.Hostname() == "" {
+		return false
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !PublicAddress(addr) {
+		return false
+	}
+	return u.Scheme == "https" || u.Scheme == "http"
+}
+
+// reservedNetworks are the special purpose ranges the netip predicates do
+// not cover.
+var reservedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // this network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved and broadcast
+}
+
+// PublicAddress reports whether a webhook may be delivered to addr. Loopback,
+// private, link-local, multicast, unspecified and reserved addresses are
+// refused, so the webhooks cannot reach the services next to this one or
+// the cloud metadata endpoint. IPv4-mapped IPv6 addresses are checked as
+// IPv4.
+func PublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks an overdraft policy about to be set.
+func (p Policy) Validate() error {
+	var errs pkgError.ValidationError
+	if !p.OverdraftPolicy.Valid() {
+		errs.Add("overdraft_policy", fmt.Sprintf("unknown overdraft policy %q", p.OverdraftPolicy))
+	}
+	if p.OverdraftLimit < 0 {
+		errs.Add("overdraft_limit", "must not be negative")
+	}
+	return errs.Err()
+}
+
+// validEmail accepts a bare address, without display name.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+func (p OverdraftPolicy) Valid() bool {
+	switch p {
+	case OverdraftFlag, OverdraftAllow, OverdraftReject:
+		return true
+	}
+	return false
+}
+
+// Overdrawn tells whether the debit amount takes balance below what the
+// policy of the account allows. Credits never overdraw an account.
+func (a Account) Overdrawn(balance, amount float64) bool {
+	if amount >= 0 {
+		return false
+	}
+	floor := 0.0
+	if a.OverdraftPolicy == OverdraftAllow {
+		floor = -a.OverdraftLimit
+	}
+	return balance+amount < floor
+}
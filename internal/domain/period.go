@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+type Granularity string
+
+const (
+	GranularityDay     Granularity = "day"
+	GranularityWeek    Granularity = "week"
+	GranularityMonth   Granularity = "month"
+	GranularityQuarter Granularity = "quarter"
+	GranularityYear    Granularity = "year"
+)
+
+// Granularities lists every granularity the summaries are projected at.
+var Granularities = []Granularity{GranularityDay, GranularityWeek, GranularityMonth, GranularityQuarter, GranularityYear}
+
+func (g Granularity) Valid() bool {
+	for _, granularity := range Granularities {
+		if g == granularity {
+			return true
+		}
+	}
+	return false
+}
+
+// Start returns the first calendar day of the period holding the calendar day
+// of t, in t's location, as midnight UTC. Weeks start on Monday.
+func (g Granularity) Start(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch g {
+	case GranularityDay:
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	case GranularityWeek:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, time.UTC)
+	case GranularityQuarter:
+		return time.Date(year, month-(month-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case GranularityYear:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Key identifies the period holding t: "2023-10-05", "2023-W40", "2023-10",
+// "2023-Q4" or "2023". Keys of one granularity sort in time order.
+func (g Granularity) Key(t time.Time) string {
+	switch g {
+	case GranularityDay:
+		return t.Format("2006-01-02")
+	case GranularityWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case GranularityQuarter:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())+2)/3)
+	case GranularityYear:
+		return t.Format("2006")
+	default:
+		return t.Format("2006-01")
+	}
+}
+
+// CivilDate returns the calendar day of t, in t's location, as midnight UTC.
+// Period starts are stored as calendar days, not instants, so a period keeps
+// its day whatever the timezone it was bucketed in.
+func CivilDate(t time.Time) time.Time {
+	return GranularityDay.Start(t)
+}
+
+// DayIn returns the instant the calendar day of date begins in location.
+func DayIn(date time.Time, location *time.Location) time.Time {
+	year, month, day := date.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, location)
+	if start.Day() != day {
+		// midnight was skipped by a daylight saving change, the day begins
+		// when the new offset takes effect
+		_, start = start.ZoneBounds()
+	}
+	return start
+}
+
+// ParsePeriod returns the granularity and the start, in UTC, of a period key.
+func ParsePeriod(key string) (Granularity, time.Time, error) {
+	var year, number int
+	if t, err := time.Parse("2006-01-02", key); err == nil {
+		return GranularityDay, t, nil
+	}
+	if t, err := time.Parse("2006-01", key); err == nil {
+		return GranularityMonth, t, nil
+	}
+	if t, err := time.Parse("2006", key); err == nil && len(key) == 4 {
+		return GranularityYear, t, nil
+	}
+	if _, err := fmt.Sscanf(key, "%4d-Q%1d", &year, &number); err == nil && number >= 1 && number <= 4 {
+		return GranularityQuarter, time.Date(year, time.Month(number*3-2), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	if _, err := fmt.Sscanf(key, "%4d-W%2d", &year, &number); err == nil && number >= 1 && number <= 53 {
+		// January 4th always falls in the first ISO week.
+		first := GranularityWeek.Start(time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC))
+		return GranularityWeek, first.AddDate(0, 0, 7*(number-1)), nil
+	}
+	return "", time.Time{}, fmt.Errorf("unknown period %q", key)
+}
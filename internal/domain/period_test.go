@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return location
+}
+
+func TestDayIn(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	santiago := mustLoadLocation(t, "America/Santiago")
+	tests := []struct {
+		name     string
+		date     time.Time
+		location *time.Location
+		want     time.Time
+	}{
+		{name: "utc", date: time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC), location: time.UTC,
+			want: time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "spring forward after midnight", date: time.Date(2023, 3, 12, 0, 0, 0, 0, time.UTC), location: newYork,
+			want: time.Date(2023, 3, 12, 5, 0, 0, 0, time.UTC)},
+		{name: "fall back after midnight", date: time.Date(2023, 11, 5, 0, 0, 0, 0, time.UTC), location: newYork,
+			want: time.Date(2023, 11, 5, 4, 0, 0, 0, time.UTC)},
+		// Chile moves from midnight to 01:00, the day begins at 01:00 local
+		{name: "midnight skipped", date: time.Date(2023, 9, 3, 0, 0, 0, 0, time.UTC), location: santiago,
+			want: time.Date(2023, 9, 3, 4, 0, 0, 0, time.UTC)},
+		// and back from midnight to 23:00, the day begins after the hour repeated
+		{name: "fall back at midnight", date: time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC), location: santiago,
+			want: time.Date(2023, 4, 2, 4, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DayIn(tt.date, tt.location)
+			if !got.Equal(tt.want) {
+				t.Fatalf("DayIn = %s, want %s", got.UTC(), tt.want)
+			}
+			if year, month, day := got.In(tt.location).Date(); day != tt.date.Day() || month != tt.date.Month() || year != tt.date.Year() {
+				t.Fatalf("DayIn = %s, a local day of %d-%02d-%02d", got.In(tt.location), year, month, day)
+			}
+		})
+	}
+}
+
+func TestGranularityStartAndKey(t *testing.T) {
+	newYork := mustLoadLocation(t, "America/New_York")
+	tests := []struct {
+		name        string
+		at          time.Time
+		granularity Granularity
+		start       time.Time
+		key         string
+	}{
+		{name: "day", at: time.Date(2023, 10, 5, 23, 59, 0, 0, time.UTC), granularity: GranularityDay,
+			start: time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC), key: "2023-10-05"},
+		{name: "week across years", at: time.Date(2021, 1, 2, 12, 0, 0, 0, time.UTC), granularity: GranularityWeek,
+			start: time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC), key: "2020-W53"},
+		{name: "quarter", at: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), granularity: GranularityQuarter,
+			start: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC), key: "2023-Q4"},
+		{name: "year", at: time.Date(2023, 6, 15, 0, 0, 0, 0, time.UTC), granularity: GranularityYear,
+			start: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), key: "2023"},
+		// 2023-11-01 02:30 UTC is still October 31st in New York
+		{name: "month in the local day", at: time.Date(2023, 11, 1, 2, 30, 0, 0, time.UTC).In(newYork), granularity: GranularityMonth,
+			start: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC), key: "2023-10"},
+		{name: "month in utc", at: time.Date(2023, 11, 1, 2, 30, 0, 0, time.UTC), granularity: GranularityMonth,
+			start: time.Date(2023, 11, 1, 0, 0, 0, 0, time.UTC), key: "2023-11"},
+		// the first hour after spring forward and the repeated hour of fall back
+		{name: "spring forward", at: time.Date(2023, 3, 12, 3, 30, 0, 0, newYork), granularity: GranularityDay,
+			start: time.Date(2023, 3, 12, 0, 0, 0, 0, time.UTC), key: "2023-03-12"},
+		{name: "fall back", at: time.Date(2023, 11, 5, 6, 30, 0, 0, time.UTC).In(newYork), granularity: GranularityDay,
+			start: time.Date(2023, 11, 5, 0, 0, 0, 0, time.UTC), key: "2023-11-05"},
+		{name: "fall back week", at: time.Date(2023, 11, 5, 6, 30, 0, 0, time.UTC).In(newYork), granularity: GranularityWeek,
+			start: time.Date(2023, 10, 30, 0, 0, 0, 0, time.UTC), key: "2023-W44"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.granularity.Start(tt.at); !got.Equal(tt.start) || got.Location() != time.UTC {
+				t.Errorf("Start = %s, want %s", got, tt.start)
+			}
+			if got := tt.granularity.Key(tt.at); got != tt.key {
+				t.Errorf("Key = %q, want %q", got, tt.key)
+			}
+			granularity, start, err := ParsePeriod(tt.key)
+			if err != nil || granularity != tt.granularity || !start.Equal(tt.start) {
+				t.Errorf("ParsePeriod(%q) = %s, %s, %v, want %s, %s", tt.key, granularity, start, err, tt.granularity, tt.start)
+			}
+		})
+	}
+}
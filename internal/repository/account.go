@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+const (
+	accountColumns = "SELECT id, name, email, locale, timezone, status, channels, COALESCE(webhook_url, ''), overdraft_policy, overdraft_limit FROM accounts"
+	getAccount     = accountColumns + " WHERE id = ?;"
+	getBalance     = "SELECT id, amount, last_updated FROM accounts WHERE id = ?;"
+	accountByEmail = "SELECT id FROM accounts WHERE email = ?;"
+
+	defaultAccountLimit = 50
+	maxAccountLimit     = 200
+)
+
+func (r Repository) GetAccount(ctx context.Context, accountID domain.AccountID) (domain.Account, error) {
+	account, err := scanAccount(r.mysql.QueryRowContext(ctx, getAccount, string(accountID)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Account{}, pkgError.NotFound("account")
+	}
+	return account, err
+}
+
+// ListAccounts reads a page of the accounts projection ordered by ID, the
+// cursor is the last ID of the previous page.
+func (r Repository) ListAccounts(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if filter.Email != "" {
+		conditions = append(conditions, "email = ?")
+		args = append(args, normalizeEmail(filter.Email))
+	}
+	if filter.Name != "" {
+		conditions = append(conditions, "name LIKE ?")
+		args = append(args, likeEscaper.Replace(filter.Name)+"%")
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Cursor != "" {
+		after, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+		if err != nil || len(after) == 0 {
+			return domain.AccountPage{}, pkgError.ErrInvalidCursor
+		}
+		conditions = append(conditions, "id > ?")
+		args = append(args, string(after))
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAccountLimit
+	}
+	if limit > maxAccountLimit {
+		limit = maxAccountLimit
+	}
+
+	query := accountColumns
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id LIMIT ?;"
+	args = append(args, limit+1)
+
+	rows, err := r.mysql.QueryContext(ctx, query, args...)
+	if err != nil {
+		return domain.AccountPage{}, err
+	}
+	defer rows.Close()
+
+	page := domain.AccountPage{Items: make([]domain.Account, 0, limit)}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return domain.AccountPage{}, err
+		}
+		page.Items = append(page.Items, account)
+	}
+	if err = rows.Err(); err != nil {
+		return domain.AccountPage{}, err
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(page.Items[limit-1].ID))
+	}
+	return page, nil
+}
+
+// emailTaken tells whether an account of the projection already has the
+// email.
+func (r Repository) emailTaken(ctx context.Context, email string) (bool, error) {
+	var id string
+	err := r.mysql.QueryRowContext(ctx, accountByEmail, email).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r Repository) GetBalance(ctx context.Context, accountID domain.AccountID) (domain.Balance, error) {
+	var balance domain.Balance
+	err := r.mysql.QueryRowContext(ctx, getBalance, string(accountID)).
+		Scan(&balance.AccountID, &balance.Amount, &balance.LastUpdated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Balance{}, pkgError.NotFound("account")
+	}
+	return balance, err
+}
+
+func (r Repository) GetSummaries(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) ([]domain.Summary, error) {
+	if _, err := r.GetAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	return r.summaries(ctx, accountID, granularity, start, end)
+}
+
+// UpdatePreferences emits the event that changes the notification channels of
+// the account.
+func (r Repository) UpdatePreferences(ctx context.Context, preferences domain.Preferences) error {
+	if _, err := r.GetAccount(ctx, preferences.AccountID); err != nil {
+		return err
+	}
+	// The same preferences may be set more than once, so the hash covers the
+	// whole event instead of only its payload.
+	event := newModel(updatePreferences, preferences.AccountID.String(), preferences, "")
+	event.Hash = calculateHash(event)
+	return r.apply(ctx, event)
+}
+
+// UpdatePolicy emits the event that changes the overdraft policy of the
+// account.
+func (r Repository) UpdatePolicy(ctx context.Context, policy domain.Policy) error {
+	if _, err := r.GetAccount(ctx, policy.AccountID); err != nil {
+		return err
+	}
+	event := newModel(updatePolicy, policy.AccountID.String(), policy, "")
+	event.Hash = calculateHash(event)
+	return r.apply(ctx, event)
+}
+
+func scanAccount(row interface{ Scan(...any) error }) (domain.Account, error) {
+	var (
+		account  domain.Account
+		channels string
+	)
+	err := row.Scan(&account.ID, &account.Name, &account.Email, &account.Locale, &account.Timezone, &account.Status, &channels, &account.WebhookURL,
+		&account.OverdraftPolicy, &account.OverdraftLimit)
+	account.Channels = parseChannels(channels)
+	return account, err
+}
+
+// normalizeEmail is the form emails are stored and compared in.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func parseChannels(value string) []domain.Channel {
+	var channels []domain.Channel
+	for _, channel := range strings.Split(value, ",") {
+		if channel = strings.TrimSpace(channel); channel != "" {
+			channels = append(channels, domain.Channel(channel))
+		}
+	}
+	if len(channels) == 0 {
+		channels = []domain.Channel{domain.ChannelEmail}
+	}
+	return channels
+}
+
+func formatChannels(channels []domain.Channel) string {
+	values := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		values = append(values, string(channel))
+	}
+	if len(values) == 0 {
+		return string(domain.ChannelEmail)
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+// AmountStats describes the sizes of the transactions stored for the
+// account.
+func (r Repository) AmountStats(ctx context.Context, accountID domain.AccountID) (domain.AmountStats, error) {
+	coll := r.mongo.Database(eventStoreDatabase).Collection(eventStoreCollection)
+	cur, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"aggregate_id": accountID.String(),
+			"event_type":   bson.M{"$in": bson.A{saveCredit, saveDebit}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    nil,
+			"count":  bson.M{"$sum": 1},
+			"mean":   bson.M{"$avg": bson.M{"$abs": "$data.amount"}},
+			"stddev": bson.M{"$stdDevPop": bson.M{"$abs": "$data.amount"}},
+		}}},
+	})
+	if err != nil {
+		return domain.AmountStats{}, err
+	}
+	defer cur.Close(ctx)
+
+	var result struct {
+		Count  int     `bson:"count"`
+		Mean   float64 `bson:"mean"`
+		StdDev float64 `bson:"stddev"`
+	}
+	if cur.Next(ctx) {
+		err = cur.Decode(&result)
+	}
+	if err == nil {
+		err = cur.Err()
+	}
+	return domain.AmountStats{Count: result.Count, Mean: result.Mean, StdDev: result.StdDev}, err
+}
+
+// TransactionsBetween returns the transactions stored for the account dated
+// from start to end, both included.
+func (r Repository) TransactionsBetween(ctx context.Context, accountID domain.AccountID, start, end time.Time) ([]domain.Transaction, error) {
+	coll := r.mongo.Database(eventStoreDatabase).Collection(eventStoreCollection)
+	cur, err := coll.Find(ctx, bson.M{
+		"aggregate_id": accountID.String(),
+		"event_type":   bson.M{"$in": bson.A{saveCredit, saveDebit}},
+		"data.date":    bson.M{"$gte": start, "$lte": end},
+	}, options.Find().SetSort(bson.D{{Key: "data.date", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var events []historyEvent
+	if err = cur.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	transactions := make([]domain.Transaction, 0, len(events))
+	for _, event := range events {
+		transactions = append(transactions, event.Data)
+	}
+	return transactions, nil
+}
+
+// Resent tells, for every transaction, whether the event store already has
+// it or it repeats an earlier one of transactions. Storing it again does
+// nothing, sources may send the same transaction more than once.
+func (r Repository) Resent(ctx context.Context, transactions []domain.Transaction) ([]bool, error) {
+	hashes := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		hashes = append(hashes, transactionHash(transaction))
+	}
+
+	coll := r.mongo.Database(eventStoreDatabase).Collection(eventStoreCollection)
+	cur, err := coll.Find(ctx, bson.M{"hash": bson.M{"$in": hashes}}, options.Find().SetProjection(bson.M{"hash": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	seen := make(map[string]bool, len(hashes))
+	for cur.Next(ctx) {
+		var stored struct {
+			Hash string `bson:"hash"`
+		}
+		if err = cur.Decode(&stored); err != nil {
+			return nil, err
+		}
+		seen[stored.Hash] = true
+	}
+	if err = cur.Err(); err != nil {
+		return nil, err
+	}
+
+	resent := make([]bool, len(hashes))
+	for i, hash := range hashes {
+		resent[i] = seen[hash]
+		seen[hash] = true
+	}
+	return resent, nil
+}
+
+// SaveFlags emits a transaction_flagged event for every violation, once per
+// transaction and rule.
+func (r Repository) SaveFlags(ctx context.Context, violations []domain.Violation) error {
+	var err error
+	for _, violation := range violations {
+		errApply := r.apply(ctx, newModel(flagTx, violation.Transaction.AccountID.String(), violation, calculateHash(struct {
+			FlagOf string `json:"flag_of"`
+			Rule   string `json:"rule"`
+		}{transactionHash(violation.Transaction), violation.Policy})))
+		if mongo.IsDuplicateKeyError(errApply) {
+			continue
+		}
+		if errApply != nil {
+			err = errors.Join(err, errApply)
+		}
+	}
+	return err
+}
+
+// GetFlags returns the transaction_flagged events of the account whose
+// transaction is dated from start to before end, by date.
+func (r Repository) GetFlags(ctx context.Context, accountID domain.AccountID, start, end time.Time) ([]domain.Flag, error) {
+	coll := r.mongo.Database(eventStoreDatabase).Collection(eventStoreCollection)
+	cur, err := coll.Find(ctx, bson.M{
+		"aggregate_id":          accountID.String(),
+		"event_type":            flagTx,
+		"data.transaction.date": bson.M{"$gte": start, "$lt": end},
+	}, options.Find().SetSort(bson.D{{Key: "data.transaction.date", Value: 1}, {Key: "event_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var events []struct {
+		EventID string           `bson:"event_id"`
+		Time    time.Time        `bson:"time"`
+		Data    domain.Violation `bson:"data"`
+	}
+	if err = cur.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	flags := make([]domain.Flag, 0, len(events))
+	for _, event := range events {
+		flags = append(flags, domain.Flag{EventID: event.EventID, Time: event.Time, Violation: event.Data})
+	}
+	return flags, nil
+}
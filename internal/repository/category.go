@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+const (
+	categoryRuleColumns = "id, COALESCE(account_id, ''), field, match_type, pattern, category, priority, created_at, updated_at"
+
+	insertCategoryRule = "INSERT INTO category_rules (id, account_id, field, match_type, pattern, category, priority, created_at, updated_at) VALUES (?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?);"
+	updateCategoryRule = "UPDATE category_rules SET account_id = NULLIF(?, ''), field = ?, match_type = ?, pattern = ?, category = ?, priority = ?, updated_at = ? WHERE id = ?;"
+	deleteCategoryRule = "DELETE FROM category_rules WHERE id = ?;"
+	getCategoryRule    = "SELECT " + categoryRuleColumns + " FROM category_rules WHERE id = ?;"
+
+	// The rules of the account go before the global ones, then the lowest
+	// priority wins.
+	listCategoryRules = "SELECT " + categoryRuleColumns + " FROM category_rules WHERE ? = '' OR account_id = ? OR account_id IS NULL ORDER BY account_id IS NULL, priority, created_at;"
+
+	getCategorySpending = "SELECT category, SUM(credit), SUM(credit_qty), SUM(debit), SUM(debit_qty) FROM category_summaries WHERE account_id = ? AND granularity = ? AND period_start >= ? AND period_start < ? GROUP BY category ORDER BY SUM(debit), category;"
+)
+
+func (r Repository) CreateCategoryRule(ctx context.Context, rule domain.CategoryRule) (domain.CategoryRule, error) {
+	if rule.AccountID != "" {
+		if _, err := r.GetAccount(ctx, rule.AccountID); err != nil {
+			return domain.CategoryRule{}, err
+		}
+	}
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	rule.UpdatedAt = rule.CreatedAt
+	_, err := r.mysql.ExecContext(ctx, insertCategoryRule, rule.ID, string(rule.AccountID), rule.Field, rule.Match,
+		rule.Pattern, rule.Category, rule.Priority, rule.CreatedAt, rule.UpdatedAt)
+	if err != nil {
+		return domain.CategoryRule{}, err
+	}
+	return rule, nil
+}
+
+func (r Repository) UpdateCategoryRule(ctx context.Context, rule domain.CategoryRule) (domain.CategoryRule, error) {
+	current, err := r.GetCategoryRule(ctx, rule.ID)
+	if err != nil {
+		return domain.CategoryRule{}, err
+	}
+	if rule.AccountID != "" {
+		if _, err = r.GetAccount(ctx, rule.AccountID); err != nil {
+			return domain.CategoryRule{}, err
+		}
+	}
+	rule.CreatedAt = current.CreatedAt
+	rule.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+	_, err = r.mysql.ExecContext(ctx, updateCategoryRule, string(rule.AccountID), rule.Field, rule.Match,
+		rule.Pattern, rule.Category, rule.Priority, rule.UpdatedAt, rule.ID)
+	if err != nil {
+		return domain.CategoryRule{}, err
+	}
+	return rule, nil
+}
+
+func (r Repository) DeleteCategoryRule(ctx context.Context, id string) error {
+	result, err := r.mysql.ExecContext(ctx, deleteCategoryRule, id)
+	if err != nil {
+		return err
+	}
+	if deleted, err := result.RowsAffected(); err == nil && deleted == 0 {
+		return pkgError.NotFound("category rule")
+	}
+	return nil
+}
+
+func (r Repository) GetCategoryRule(ctx context.Context, id string) (domain.CategoryRule, error) {
+	rule, err := scanCategoryRule(r.mysql.QueryRowContext(ctx, getCategoryRule, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.CategoryRule{}, pkgError.NotFound("category rule")
+	}
+	return rule, err
+}
+
+// ListCategoryRules returns the rules applied to the account in the order
+// they are tried, every rule when accountID is empty.
+func (r Repository) ListCategoryRules(ctx context.Context, accountID domain.AccountID) ([]domain.CategoryRule, error) {
+	return categoryRules(ctx, r.mysql, accountID)
+}
+
+// GetCategorySpending adds up the categories of the periods of the
+// granularity that begin before end, starting with the one holding start.
+func (r Repository) GetCategorySpending(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) ([]domain.CategorySpending, error) {
+	rows, err := r.mysql.QueryContext(ctx, getCategorySpending, string(accountID), granularity,
+		granularity.Start(start.UTC()), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var spending []domain.CategorySpending
+	for rows.Next() {
+		var category domain.CategorySpending
+		if err = rows.Scan(&category.Category, &category.Credit, &category.CreditQty, &category.Debit, &category.DebitQty); err != nil {
+			return nil, err
+		}
+		spending = append(spending, category)
+	}
+	return spending, rows.Err()
+}
+
+// CategoryRules returns the rules applied to the transactions of the account,
+// in the order they are tried.
+func (p ProjectionAccount) CategoryRules(ctx context.Context, accountID domain.AccountID) ([]domain.CategoryRule, error) {
+	return categoryRules(ctx, p.mysql, accountID)
+}
+
+func categoryRules(ctx context.Context, db *sql.DB, accountID domain.AccountID) ([]domain.CategoryRule, error) {
+	rows, err := db.QueryContext(ctx, listCategoryRules, string(accountID), string(accountID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]domain.CategoryRule, 0)
+	for rows.Next() {
+		rule, err := scanCategoryRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+func scanCategoryRule(row interface{ Scan(...any) error }) (domain.CategoryRule, error) {
+	var rule domain.CategoryRule
+	err := row.Scan(&rule.ID, &rule.AccountID, &rule.Field, &rule.Match, &rule.Pattern, &rule.Category,
+		&rule.Priority, &rule.CreatedAt, &rule.UpdatedAt)
+	return rule, err
+}
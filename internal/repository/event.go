@@ -8,10 +8,12 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+	"github.com/castiglionimax/process-csv/pkg/i18n"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/google/uuid"
-	"github.com/harranali/mailing"
 	"github.com/minio/minio-go/v7"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"log"
 )
@@ -22,30 +24,71 @@ type (
 	}
 
 	Repository struct {
-		producer *kafka.Producer
+		producer producer
+		events   eventStore
 		mongo    *mongo.Client
 		topic    string
 		mysql    *sql.DB
 		minio    *minio.Client
-		mailer   *mailing.Mailer
+		renderer *Renderer
 	}
 )
 
-func NewRepository(producer *kafka.Producer, topic string, mongo *mongo.Client, mysql *sql.DB, minio *minio.Client, mailer *mailing.Mailer) *Repository {
-	return &Repository{producer: producer, topic: topic, mongo: mongo, mysql: mysql, minio: minio, mailer: mailer}
+func NewRepository(producer *kafka.Producer, topic string, mongo *mongo.Client, mysql *sql.DB, minio *minio.Client, renderer *Renderer) *Repository {
+	return &Repository{producer: producer, events: mongoEventStore{mongo}, topic: topic, mongo: mongo, mysql: mysql,
+		minio: minio, renderer: renderer}
 }
 
 const (
-	createAccount = "account_created"
-	saveDebit     = "debit_saved"
-	saveCredit    = "credit_saved"
+	createAccount     = "account_created"
+	updatePreferences = "account_preferences_updated"
+	updatePolicy      = "account_policy_updated"
+	rejectTx          = "transaction_rejected"
+	flagTx            = "transaction_flagged"
+	saveDebit         = "debit_saved"
+	saveCredit        = "credit_saved"
+	reverseTx         = "transaction_reversed"
+
+	eventStoreDatabase   = "event_store"
+	eventStoreCollection = "accounts"
 )
 
+// CreateAccount emits the event that opens a new account. Emails are unique:
+// the projection catches the accounts already there and the hash of the event,
+// which covers only the email, the ones created at the same time.
 func (r Repository) CreateAccount(ctx context.Context, account domain.Account) (domain.AccountID, error) {
+	account.Email = normalizeEmail(account.Email)
+	taken, err := r.emailTaken(ctx, account.Email)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", pkgError.ErrDuplicateEmail
+	}
+
 	account.ID = domain.AccountID(uuid.New().String())
-	eventModel := newModel(createAccount, account.ID.String(), account, calculateHash(account))
+	account.Status = domain.AccountActive
+	if account.Locale == "" {
+		account.Locale = i18n.DefaultLocale
+	}
+	if account.Timezone == "" {
+		account.Timezone = domain.DefaultTimezone
+	}
+	if account.OverdraftPolicy == "" {
+		account.OverdraftPolicy = domain.OverdraftFlag
+	}
+	if len(account.Channels) == 0 {
+		account.Channels = []domain.Channel{domain.ChannelEmail}
+	}
+	eventModel := newModel(createAccount, account.ID.String(), account, calculateHash(struct {
+		Email string `json:"email"`
+	}{account.Email}))
 
-	if err := r.apply(ctx, eventModel); err != nil {
+	err = r.apply(ctx, eventModel)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", pkgError.ErrDuplicateEmail
+	}
+	if err != nil {
 		return "", err
 	}
 	return account.ID, nil
@@ -62,7 +105,11 @@ func (r Repository) SaveTransactions(ctx context.Context, transactions []domain.
 		} else {
 			transactionType = saveDebit
 		}
-		errApply := r.apply(ctx, newModel(transactionType, transaction.AccountID.String(), transaction, calculateHash(transaction)))
+		errApply := r.apply(ctx, newModel(transactionType, transaction.AccountID.String(), transaction, transactionHash(transaction)))
+		if mongo.IsDuplicateKeyError(errApply) {
+			// already stored, sources may send the same transaction again
+			continue
+		}
 		if errApply != nil {
 			err = errors.Join(err, errApply)
 		}
@@ -70,48 +117,201 @@ func (r Repository) SaveTransactions(ctx context.Context, transactions []domain.
 	return err
 }
 
-func (r Repository) apply(ctx context.Context, event interface{}) error {
-	coll := r.mongo.Database("event_store").Collection("accounts")
-
-	session, err := r.mongo.StartSession()
+// RehydrateBalance returns the balance of the account from its events in the
+// event store, including the ones not projected yet.
+func (r Repository) RehydrateBalance(ctx context.Context, accountID domain.AccountID) (float64, error) {
+	coll := r.mongo.Database(eventStoreDatabase).Collection(eventStoreCollection)
+	cur, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"aggregate_id": accountID.String(),
+			"event_type":   bson.M{"$in": bson.A{saveCredit, saveDebit, reverseTx}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": nil,
+			"balance": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$event_type", reverseTx}},
+				bson.M{"$multiply": bson.A{-1, "$data.transaction.amount"}},
+				"$data.amount",
+			}}},
+		}}},
+	})
 	if err != nil {
-		return err
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var result struct {
+		Balance float64 `bson:"balance"`
 	}
+	if cur.Next(ctx) {
+		err = cur.Decode(&result)
+	}
+	if err == nil {
+		err = cur.Err()
+	}
+	return result.Balance, err
+}
 
-	defer session.EndSession(ctx)
+// SaveRejections emits a transaction_rejected event for every violation that
+// kept a transaction out, once per transaction.
+func (r Repository) SaveRejections(ctx context.Context, violations []domain.Violation) error {
+	var err error
+	for _, violation := range violations {
+		errApply := r.apply(ctx, newModel(rejectTx, violation.Transaction.AccountID.String(), violation, calculateHash(struct {
+			RejectionOf string `json:"rejection_of"`
+		}{transactionHash(violation.Transaction)})))
+		if mongo.IsDuplicateKeyError(errApply) {
+			continue
+		}
+		if errApply != nil {
+			err = errors.Join(err, errApply)
+		}
+	}
+	return err
+}
+
+// ReverseTransaction emits the event undoing the transaction of the credit or
+// debit event eventID and returns its ID. A transaction is reversed once. Both
+// sides of a transfer are reversed together.
+func (r Repository) ReverseTransaction(ctx context.Context, eventID, reason string) (string, error) {
+	coll := r.mongo.Database(eventStoreDatabase).Collection(eventStoreCollection)
+	transactions := bson.M{"$in": bson.A{saveCredit, saveDebit}}
 
-	if err = session.StartTransaction(); err != nil {
-		return err
+	var original historyEvent
+	err := coll.FindOne(ctx, bson.M{"event_id": eventID, "event_type": transactions}).Decode(&original)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", pkgError.NotFound("transaction")
+	}
+	if err != nil {
+		return "", err
 	}
 
-	return mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
-		_, err = coll.InsertOne(ctx, event)
+	reversed := []historyEvent{original}
+	if original.Data.TransferID != "" {
+		cur, err := coll.Find(ctx, bson.M{"event_type": transactions, "data.transferid": original.Data.TransferID,
+			"event_id": bson.M{"$ne": eventID}})
 		if err != nil {
-			return err
+			return "", err
 		}
+		var counterparts []historyEvent
+		if err = cur.All(ctx, &counterparts); err != nil {
+			return "", err
+		}
+		reversed = append(reversed, counterparts...)
+	}
 
-		enAccount, err := json.Marshal(event)
+	events := make([]any, 0, len(reversed))
+	for _, event := range reversed {
+		reversal := domain.Reversal{EventID: event.EventID, Transaction: event.Data, Reason: reason}
+		events = append(events, newModel(reverseTx, event.AggregateID, reversal, calculateHash(struct {
+			ReversalOf string `json:"reversal_of"`
+		}{event.EventID})))
+	}
+	err = r.apply(ctx, events...)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", pkgError.ErrAlreadyReversed
+	}
+	if err != nil {
+		return "", err
+	}
+	return events[0].(Model).EventID, nil
+}
+
+// SaveTransfer emits the two sides of the transfer, a debit on the source
+// account and a credit on the destination, sharing the transfer ID. Both are
+// stored at once; the reference, when set, keeps the transfer from being
+// stored twice.
+func (r Repository) SaveTransfer(ctx context.Context, transfer domain.Transfer) (string, error) {
+	transfer.ID = uuid.New().String()
+	debit := domain.Transaction{
+		AccountID:    transfer.From,
+		Date:         transfer.Date,
+		Amount:       -transfer.Amount,
+		Type:         domain.TransactionTransfer,
+		Description:  transfer.Description,
+		Reference:    transfer.Reference,
+		TransferID:   transfer.ID,
+		Counterparty: transfer.To,
+	}
+	credit := debit
+	credit.AccountID, credit.Amount, credit.Counterparty = transfer.To, transfer.Amount, transfer.From
+
+	// the hash of the debit decides, the credit only follows it
+	err := r.apply(ctx,
+		newModel(saveDebit, debit.AccountID.String(), debit, transactionHash(debit)),
+		newModel(saveCredit, credit.AccountID.String(), credit, transactionHash(credit)))
+	if mongo.IsDuplicateKeyError(err) {
+		return "", pkgError.ErrDuplicateTransfer
+	}
+	if err != nil {
+		return "", err
+	}
+	return transfer.ID, nil
+}
+
+// apply stores the events, in order, and publishes them. Events stored
+// together are published together: they are inserted in one transaction,
+// which is only committed once every event was delivered to the broker, so a
+// failure on any of them leaves none stored.
+func (r Repository) apply(ctx context.Context, events ...any) error {
+	return r.events.transact(ctx, func(sc context.Context) error {
+		if err := r.events.insert(sc, events); err != nil {
+			return unavailable(err)
+		}
+		return r.publish(sc, events)
+	})
+}
+
+// publish produces the events and waits for the broker to acknowledge them.
+func (r Repository) publish(ctx context.Context, events []any) error {
+	deliveryChan := make(chan kafka.Event, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
 		if err != nil {
 			return err
 		}
-
-		deliveryChan := make(chan kafka.Event, 10000)
 		err = r.producer.Produce(&kafka.Message{
 			TopicPartition: kafka.TopicPartition{Topic: &r.topic, Partition: kafka.PartitionAny},
-			Value:          enAccount},
+			Value:          value},
 			deliveryChan,
 		)
-
 		if err != nil {
-			_ = session.AbortTransaction(ctx)
-			return err
+			return pkgError.ErrUnavailable.Wrap(err)
 		}
+	}
 
-		if err = session.CommitTransaction(sc); err != nil {
-			return err
+	for range events {
+		select {
+		case <-ctx.Done():
+			return pkgError.ErrUnavailable.Wrap(ctx.Err())
+		case e := <-deliveryChan:
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				return pkgError.ErrUnavailable.Wrap(m.TopicPartition.Error)
+			}
 		}
-		return nil
-	})
+	}
+	return nil
+}
+
+// unavailable marks the failures reaching the event store, so they are told
+// apart from the errors of the request.
+func unavailable(err error) error {
+	if mongo.IsNetworkError(err) || mongo.IsTimeout(err) {
+		return pkgError.ErrUnavailable.Wrap(err)
+	}
+	return err
+}
+
+// transactionHash is the idempotency key of a transaction: its external
+// reference when it has one, the whole payload otherwise.
+func transactionHash(transaction domain.Transaction) string {
+	if transaction.Reference == "" {
+		return calculateHash(transaction)
+	}
+	return calculateHash(struct {
+		AccountID domain.AccountID `json:"account_id"`
+		Reference string           `json:"reference"`
+	}{transaction.AccountID, transaction.Reference})
 }
 
 func calculateHash[T any](x T) string {
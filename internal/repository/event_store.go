@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type (
+	// eventStore keeps the events. insert only takes effect when it runs
+	// within transact and fn succeeds.
+	eventStore interface {
+		transact(ctx context.Context, fn func(ctx context.Context) error) error
+		insert(ctx context.Context, events []any) error
+	}
+
+	mongoEventStore struct {
+		client *mongo.Client
+	}
+)
+
+// transact runs fn in a transaction, committed when fn succeeds and aborted
+// otherwise. fn must use the context it is given.
+func (s mongoEventStore) transact(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := s.client.StartSession()
+	if err != nil {
+		return unavailable(err)
+	}
+	defer session.EndSession(ctx)
+
+	if err = session.StartTransaction(); err != nil {
+		return unavailable(err)
+	}
+	return mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+		if err := fn(sc); err != nil {
+			_ = session.AbortTransaction(context.Background())
+			return err
+		}
+		return unavailable(session.CommitTransaction(sc))
+	})
+}
+
+func (s mongoEventStore) insert(ctx context.Context, events []any) error {
+	_, err := s.client.Database(eventStoreDatabase).Collection(eventStoreCollection).InsertMany(ctx, events)
+	return err
+}
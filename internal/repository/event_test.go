@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+// memoryEventStore behaves like the event store collection: hashes are
+// unique, inserts are ordered and only kept when the transaction commits.
+type memoryEventStore struct {
+	stored  []Model
+	pending []Model
+}
+
+func (s *memoryEventStore) transact(ctx context.Context, fn func(ctx context.Context) error) error {
+	s.pending = nil
+	if err := fn(ctx); err != nil {
+		s.pending = nil
+		return err
+	}
+	s.stored = append(s.stored, s.pending...)
+	s.pending = nil
+	return nil
+}
+
+func (s *memoryEventStore) insert(_ context.Context, events []any) error {
+	for _, event := range events {
+		model := event.(Model)
+		for _, existing := range append(s.stored, s.pending...) {
+			if existing.Hash == model.Hash {
+				return mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000, Message: "duplicate key"}}}
+			}
+		}
+		s.pending = append(s.pending, model)
+	}
+	return nil
+}
+
+type memoryProducer struct {
+	produced    []*kafka.Message
+	produceErr  error
+	deliveryErr error
+}
+
+func (p *memoryProducer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	if p.produceErr != nil {
+		return p.produceErr
+	}
+	p.produced = append(p.produced, msg)
+	deliveryChan <- &kafka.Message{TopicPartition: kafka.TopicPartition{Error: p.deliveryErr}}
+	return nil
+}
+
+func newMemoryRepository(store *memoryEventStore, producer *memoryProducer) Repository {
+	return Repository{producer: producer, events: store, topic: "EventQueue"}
+}
+
+func TestSaveTransferStoresBothSides(t *testing.T) {
+	store, producer := &memoryEventStore{}, &memoryProducer{}
+	r := newMemoryRepository(store, producer)
+
+	id, err := r.SaveTransfer(context.Background(), domain.Transfer{
+		From: "from", To: "to", Amount: 10, Date: time.Now(), Reference: "ref-1",
+	})
+	if err != nil {
+		t.Fatalf("SaveTransfer: %v", err)
+	}
+	if len(store.stored) != 2 || len(producer.produced) != 2 {
+		t.Fatalf("stored %d and published %d events, want 2 and 2", len(store.stored), len(producer.produced))
+	}
+	for _, event := range store.stored {
+		if event.Data.(domain.Transaction).TransferID != id {
+			t.Errorf("event %s has transfer %q, want %q", event.EventType, event.Data.(domain.Transaction).TransferID, id)
+		}
+	}
+}
+
+func TestSaveTransferCreditReferenceTaken(t *testing.T) {
+	existing := domain.Transaction{AccountID: "to", Amount: 5, Date: time.Now(), Reference: "ref-1"}
+	store := &memoryEventStore{stored: []Model{newModel(saveCredit, "to", existing, transactionHash(existing))}}
+	producer := &memoryProducer{}
+	r := newMemoryRepository(store, producer)
+
+	_, err := r.SaveTransfer(context.Background(), domain.Transfer{
+		From: "from", To: "to", Amount: 10, Date: time.Now(), Reference: "ref-1",
+	})
+	if !errors.Is(err, pkgError.ErrDuplicateTransfer) {
+		t.Fatalf("SaveTransfer error = %v, want %v", err, pkgError.ErrDuplicateTransfer)
+	}
+	if len(store.stored) != 1 {
+		t.Errorf("stored %d events, want only the existing one", len(store.stored))
+	}
+	if len(producer.produced) != 0 {
+		t.Errorf("published %d events, want none", len(producer.produced))
+	}
+}
+
+func TestApplyBrokerFailure(t *testing.T) {
+	for name, producer := range map[string]*memoryProducer{
+		"produce":  {produceErr: errors.New("queue full")},
+		"delivery": {deliveryErr: errors.New("broker down")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := &memoryEventStore{}
+			r := newMemoryRepository(store, producer)
+
+			tx := domain.Transaction{AccountID: "acc", Amount: 1, Date: time.Now()}
+			err := r.apply(context.Background(), newModel(saveCredit, "acc", tx, transactionHash(tx)))
+			if !errors.Is(err, pkgError.ErrUnavailable) {
+				t.Fatalf("apply error = %v, want %v", err, pkgError.ErrUnavailable)
+			}
+			if len(store.stored) != 0 {
+				t.Errorf("stored %d events, want none", len(store.stored))
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	"github.com/castiglionimax/process-csv/pkg/i18n"
+	"github.com/castiglionimax/process-csv/pkg/pdf"
+)
+
+func (r Repository) ExportSummary(_ context.Context, account domain.Account, report domain.SummaryReport, format domain.ReportFormat) (domain.Report, error) {
+	view := newSummaryView(account, report)
+
+	var err error
+	export := domain.Report{Filename: fmt.Sprintf("summary-%s.%s", account.ID, format)}
+	switch format {
+	case domain.ReportCSV:
+		export.ContentType = "text/csv"
+		export.Content, err = csvBuilder(view)
+	case domain.ReportJSON:
+		export.ContentType = "application/json"
+		export.Content, err = json.Marshal(view)
+	case domain.ReportHTML:
+		var html string
+		export.ContentType = "text/html; charset=utf-8"
+		html, err = r.renderer.HTML(view)
+		export.Content = []byte(html)
+	case domain.ReportPDF:
+		export.ContentType = "application/pdf"
+		export.Content = pdfBuilder(view)
+	default:
+		return domain.Report{}, fmt.Errorf("unsupported format %q", format)
+	}
+	return export, err
+}
+
+func csvBuilder(view summaryView) ([]byte, error) {
+	l := i18n.For(view.Locale)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if l.DecimalSeparator() == "," {
+		writer.Comma = ';'
+	}
+	_ = writer.Write([]string{
+		l.T("summary.period"),
+		l.T("summary.movements"),
+		l.T("summary.debit"),
+		l.T("summary.debit_qty"),
+		l.T("summary.credit"),
+		l.T("summary.credit_qty"),
+		l.T("summary.transfer_in"),
+		l.T("summary.transfer_out"),
+		l.T("summary.total"),
+		l.T("summary.min_debit"),
+		l.T("summary.max_debit"),
+		l.T("summary.min_credit"),
+		l.T("summary.max_credit"),
+		l.T("summary.opening"),
+		l.T("summary.closing"),
+	})
+	row := func(period string, v domain.Statistics, opening, closing float64) []string {
+		return []string{
+			period,
+			strconv.Itoa(v.Movements),
+			l.Number(v.Debit),
+			strconv.Itoa(v.DebitQty),
+			l.Number(v.Credit),
+			strconv.Itoa(v.CreditQty),
+			l.Number(v.TransferIn),
+			l.Number(v.TransferOut),
+			l.Number(v.NetFlow),
+			l.Number(v.MinDebit),
+			l.Number(v.MaxDebit),
+			l.Number(v.MinCredit),
+			l.Number(v.MaxCredit),
+			l.Number(opening),
+			l.Number(closing),
+		}
+	}
+	for _, v := range view.Periods {
+		_ = writer.Write(row(localizedPeriod(l, v.Period), v.Statistics, v.OpeningBalance, v.ClosingBalance))
+	}
+	_ = writer.Write(row(l.T("summary.totals"), view.Totals, view.OpeningBalance, view.ClosingBalance))
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func pdfBuilder(view summaryView) []byte {
+	const (
+		margin     = 50.0
+		lineHeight = 18.0
+	)
+	columns := []float64{margin, margin + 85, margin + 135, margin + 207, margin + 279, margin + 351, margin + 423}
+	l := i18n.For(view.Locale)
+
+	doc := pdf.New()
+	y := margin
+	doc.BoldText(margin, y, 18, l.T("summary.title"))
+	y += lineHeight * 1.5
+	doc.Text(margin, y, 11, l.T("summary.balance", l.Money(view.Balance)))
+	y += lineHeight
+	doc.Text(margin, y, 11, l.T("summary.account", view.AccountID))
+	y += lineHeight
+	doc.Text(margin, y, 11, l.T("summary.opening_balance", l.Money(view.OpeningBalance)))
+	y += lineHeight * 1.5
+
+	header := func() {
+		for i, key := range []string{"summary.period", "summary.movements", "summary.debit", "summary.credit",
+			"summary.total", "summary.opening", "summary.closing"} {
+			doc.BoldText(columns[i], y, 8, l.T(key))
+		}
+		doc.Line(margin, y+4, pdf.PageWidth-margin, y+4)
+		y += lineHeight
+	}
+	header()
+	for _, v := range view.Periods {
+		if y > pdf.PageHeight-margin-lineHeight*9 {
+			doc.AddPage()
+			y = margin
+			header()
+		}
+		doc.Text(columns[0], y, 9, localizedPeriod(l, v.Period))
+		doc.Text(columns[1], y, 9, strconv.Itoa(v.Movements))
+		doc.Text(columns[2], y, 9, l.Money(v.Debit))
+		doc.Text(columns[3], y, 9, l.Money(v.Credit))
+		doc.Text(columns[4], y, 9, l.Money(v.NetFlow))
+		doc.Text(columns[5], y, 9, l.Money(v.OpeningBalance))
+		doc.Text(columns[6], y, 9, l.Money(v.ClosingBalance))
+		y += lineHeight
+	}
+
+	totals := view.Totals
+	lines := []string{
+		l.T("summary.closing_balance", l.Money(view.ClosingBalance)),
+		l.T("summary.net_flow", l.Money(totals.NetFlow)),
+		l.T("summary.average_debit", l.Money(totals.AverageDebit)),
+		l.T("summary.average_credit", l.Money(totals.AverageCredit)),
+		l.T("summary.debit_range", l.Money(totals.MinDebit), l.Money(totals.MaxDebit)),
+		l.T("summary.credit_range", l.Money(totals.MinCredit), l.Money(totals.MaxCredit)),
+	}
+	if totals.TransferInQty+totals.TransferOutQty > 0 {
+		lines = append(lines, l.T("summary.transfers", l.Money(totals.TransferIn), l.Money(totals.TransferOut)))
+	}
+	y += lineHeight / 2
+	for _, line := range lines {
+		doc.BoldText(margin, y, 11, line)
+		y += lineHeight
+	}
+	doc.Text(margin, y, 10, l.Date(view.GeneratedAt))
+
+	return doc.Bytes()
+}
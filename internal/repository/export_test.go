@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+func TestPDFBuilderPages(t *testing.T) {
+	tests := []struct {
+		name    string
+		periods int
+		pages   int
+	}{
+		{name: "one page", periods: 2, pages: 1},
+		{name: "first page full", periods: 27, pages: 1},
+		{name: "two pages", periods: 28, pages: 2},
+		{name: "three pages", periods: 80, pages: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := goldenSummaryView("en")
+			view.Periods = nil
+			for i := 0; i < tt.periods; i++ {
+				view.Periods = append(view.Periods, domain.PeriodStatistics{
+					Period:     fmt.Sprintf("%d-%02d", 2017+i/12, i%12+1),
+					Statistics: domain.Statistics{Movements: 1000 + i},
+				})
+			}
+			content := string(pdfBuilder(view))
+
+			count := regexp.MustCompile(`/Count (\d+)`).FindStringSubmatch(content)
+			if count == nil || count[1] != fmt.Sprint(tt.pages) {
+				t.Fatalf("page tree %v, want /Count %d", count, tt.pages)
+			}
+			if headers := strings.Count(content, "(Period) Tj"); headers != tt.pages {
+				t.Errorf("%d table headers, want one on each of the %d pages", headers, tt.pages)
+			}
+
+			// every row is written once, in order
+			last := -1
+			for i := 0; i < tt.periods; i++ {
+				row := fmt.Sprintf("(%d) Tj", 1000+i)
+				if strings.Count(content, row) != 1 {
+					t.Fatalf("row %d written %d times", i, strings.Count(content, row))
+				}
+				at := strings.Index(content, row)
+				if at < last {
+					t.Fatalf("row %d out of order", i)
+				}
+				last = at
+			}
+			if !strings.Contains(content[last:], "(Closing balance: ") {
+				t.Errorf("totals missing after the last row")
+			}
+		})
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/minio/minio-go/v7"
 	"log"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/castiglionimax/process-csv/internal/domain"
@@ -20,33 +19,40 @@ const (
 	contentType        = "application/zip"
 )
 
+// stagingHeader is the layout of the files waiting to be processed. Files
+// staged before the metadata columns existed only have the first three.
+var stagingHeader = []string{"account_id", "timestamp", "amount", "type", "description", "merchant", "category", "reference"}
+
 func (r Repository) SaveTransactionsInDirectory(ctx context.Context, transactions []domain.Transaction) error {
-	var csvData [][]string
-	csvData = append(csvData, []string{"account_id", "timestamp", "amount"})
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write(stagingHeader)
 	for _, tx := range transactions {
-		csvData = append(csvData, []string{
+		_ = writer.Write([]string{
 			string(tx.AccountID),
-			strconv.FormatInt(tx.Date.Unix(), 10),
-			strconv.FormatFloat(tx.Amount, 'f', -1, 64)})
+			tx.Date.UTC().Format(time.RFC3339Nano),
+			strconv.FormatFloat(tx.Amount, 'f', -1, 64),
+			string(tx.Type),
+			tx.Description,
+			tx.Merchant,
+			tx.Category,
+			tx.Reference,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
 	}
-	csvContent := convertToCSV(csvData)
+
 	_, err := r.minio.PutObject(ctx,
 		bucketTransactions,
 		fmt.Sprintf("%s%s", uuid.New().String(), ".csv"),
-		bytes.NewReader([]byte(csvContent)),
-		int64(len(csvContent)),
+		bytes.NewReader(buf.Bytes()),
+		int64(buf.Len()),
 		minio.PutObjectOptions{ContentType: contentType})
 	return err
 }
 
-func convertToCSV(data [][]string) string {
-	var csvLines []string
-	for _, line := range data {
-		csvLines = append(csvLines, strings.Join(line, ","))
-	}
-	return strings.Join(csvLines, "\n")
-}
-
 func (r Repository) GetTransactionFromDirectory(ctx context.Context) ([]domain.Transaction, error) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -69,14 +75,18 @@ func (r Repository) GetTransactionFromDirectory(ctx context.Context) ([]domain.T
 		}
 
 		reader := csv.NewReader(cvs)
+		reader.FieldsPerRecord = -1
 		lines, err := reader.ReadAll()
 		if err != nil {
 			break
 		}
 
 		for _, row := range lines {
+			if len(row) < 3 {
+				continue
+			}
 
-			parseTimeStamp, err := strconv.ParseInt(row[1], 10, 64)
+			parsedDate, err := stagedTime(row[1])
 			if err != nil {
 				continue
 			}
@@ -88,15 +98,29 @@ func (r Repository) GetTransactionFromDirectory(ctx context.Context) ([]domain.T
 
 			gotten := domain.Transaction{
 				AccountID: domain.AccountID(row[0]),
-				Date:      time.Unix(parseTimeStamp, 0),
+				Date:      parsedDate,
 				Amount:    parsedAmount,
 			}
+			if len(row) >= len(stagingHeader) {
+				gotten.Type = domain.TransactionType(row[3])
+				gotten.Description, gotten.Merchant, gotten.Category, gotten.Reference = row[4], row[5], row[6], row[7]
+			}
 			transactions = append(transactions, gotten)
 		}
 
 	}
 	return transactions, nil
 }
+
+// stagedTime reads the timestamps of staged files, RFC 3339 or the epoch
+// seconds written by older versions.
+func stagedTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
 func (r Repository) DeleteTransactionsInDirectory(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+type (
+	historyEvent struct {
+		EventID     string             `bson:"event_id"`
+		EventType   string             `bson:"event_type"`
+		AggregateID string             `bson:"aggregate_id"`
+		Time        time.Time          `bson:"time"`
+		Data        domain.Transaction `bson:"data"`
+	}
+
+	historyCursor struct {
+		Time    time.Time `json:"t"`
+		EventID string    `json:"id"`
+	}
+)
+
+func (r Repository) GetTransactions(ctx context.Context, accountID domain.AccountID, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	query := bson.M{"aggregate_id": accountID.String()}
+
+	switch filter.Type {
+	case domain.TransactionCredit:
+		query["event_type"] = saveCredit
+	case domain.TransactionDebit:
+		query["event_type"] = saveDebit
+	case "":
+		query["event_type"] = bson.M{"$in": bson.A{saveCredit, saveDebit}}
+	default:
+		// fees, refunds and transfers are stored as credits or debits
+		query["event_type"] = bson.M{"$in": bson.A{saveCredit, saveDebit}}
+		query["data.type"] = filter.Type
+	}
+
+	date := bson.M{}
+	if !filter.Start.IsZero() {
+		date["$gte"] = filter.Start
+	}
+	if !filter.End.IsZero() {
+		date["$lt"] = filter.End
+	}
+	if len(date) > 0 {
+		query["data.date"] = date
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return domain.TransactionPage{}, err
+		}
+		query["$or"] = bson.A{
+			bson.M{"time": bson.M{"$gt": cursor.Time}},
+			bson.M{"time": cursor.Time, "event_id": bson.M{"$gt": cursor.EventID}},
+		}
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	if limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+
+	coll := r.mongo.Database(eventStoreDatabase).Collection(eventStoreCollection)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "time", Value: 1}, {Key: "event_id", Value: 1}}).
+		SetLimit(int64(limit + 1))
+
+	cur, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return domain.TransactionPage{}, err
+	}
+	defer cur.Close(ctx)
+
+	var events []historyEvent
+	if err = cur.All(ctx, &events); err != nil {
+		return domain.TransactionPage{}, err
+	}
+
+	page := domain.TransactionPage{Items: make([]domain.TransactionEvent, 0, len(events))}
+	if len(events) > limit {
+		events = events[:limit]
+		last := events[len(events)-1]
+		page.NextCursor = encodeCursor(historyCursor{Time: last.Time, EventID: last.EventID})
+	}
+
+	reversedBy, err := r.reversals(ctx, events)
+	if err != nil {
+		return domain.TransactionPage{}, err
+	}
+
+	for _, event := range events {
+		page.Items = append(page.Items, domain.TransactionEvent{
+			EventID:     event.EventID,
+			EventType:   event.EventType,
+			AggregateID: domain.AccountID(event.AggregateID),
+			Time:        event.Time,
+			Data:        event.Data,
+			ReversedBy:  reversedBy[event.EventID],
+		})
+	}
+	return page, nil
+}
+
+// reversals returns the IDs of the events reversing the given ones, by the ID
+// of the event they reverse.
+func (r Repository) reversals(ctx context.Context, events []historyEvent) (map[string]string, error) {
+	reversedBy := make(map[string]string)
+	if len(events) == 0 {
+		return reversedBy, nil
+	}
+	ids := make(bson.A, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.EventID)
+	}
+
+	coll := r.mongo.Database(eventStoreDatabase).Collection(eventStoreCollection)
+	cur, err := coll.Find(ctx, bson.M{"event_type": reverseTx, "data.eventid": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var reversals []struct {
+		EventID string          `bson:"event_id"`
+		Data    domain.Reversal `bson:"data"`
+	}
+	if err = cur.All(ctx, &reversals); err != nil {
+		return nil, err
+	}
+	for _, reversal := range reversals {
+		reversedBy[reversal.Data.EventID] = reversal.EventID
+	}
+	return reversedBy, nil
+}
+
+func encodeCursor(cursor historyCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (historyCursor, error) {
+	var cursor historyCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, pkgError.ErrInvalidCursor
+	}
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return cursor, pkgError.ErrInvalidCursor.Wrap(err)
+	}
+	return cursor, nil
+}
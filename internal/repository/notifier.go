@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	"github.com/castiglionimax/process-csv/pkg/email"
+)
+
+type (
+	EmailNotifier struct {
+		transport *email.SMTPTransport
+	}
+
+	// WebhookNotifier posts the summary as JSON. Requests carry an
+	// X-Signature header with the hex HMAC-SHA256 of "<timestamp>.<body>",
+	// keyed with the shared secret, and the timestamp in X-Signature-Timestamp.
+	WebhookNotifier struct {
+		client *http.Client
+		secret []byte
+	}
+
+	// FileNotifier writes every delivery as an .eml file into a spool
+	// directory, meant for development and tests.
+	FileNotifier struct {
+		dir string
+	}
+
+	webhookPayload struct {
+		ID        string           `json:"id"`
+		AccountID domain.AccountID `json:"account_id"`
+		Subject   string           `json:"subject"`
+		SentAt    time.Time        `json:"sent_at"`
+		Summary   json.RawMessage  `json:"summary"`
+	}
+)
+
+func NewEmailNotifier(transport *email.SMTPTransport) *EmailNotifier {
+	return &EmailNotifier{transport: transport}
+}
+
+func (n *EmailNotifier) Channel() domain.Channel {
+	return domain.ChannelEmail
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, delivery domain.Delivery) error {
+	return n.transport.Send(ctx, deliveryMessage(delivery))
+}
+
+// NewWebhookClient returns the client the webhooks are posted with. It
+// does not follow redirects, they could lead the requests anywhere, and it
+// refuses to connect to an address domain.PublicAddress refuses. The check
+// runs on the resolved address at dial time, so a host name pointing at an
+// internal address is refused as well.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// through a proxy the address dialed would be the proxy's
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// dialPublic is the net.Dialer Control of the webhook client.
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("webhook: dialing %s: %w", address, err)
+	}
+	if !domain.PublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("webhook: %s is not a public address", addrPort.Addr())
+	}
+	return nil
+}
+
+// NewWebhookNotifier refuses an empty secret, receivers could not tell the
+// summaries from forged ones.
+func NewWebhookNotifier(client *http.Client, secret string) (*WebhookNotifier, error) {
+	if secret == "" {
+		return nil, errors.New("webhook notifier: empty signing secret")
+	}
+	return &WebhookNotifier{client: client, secret: []byte(secret)}, nil
+}
+
+func (n *WebhookNotifier) Channel() domain.Channel {
+	return domain.ChannelWebhook
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, delivery domain.Delivery) error {
+	// recipients queued before webhook URLs were validated are checked again
+	if !domain.ValidWebhookURL(delivery.Recipient) {
+		return fmt.Errorf("webhook %q is not an absolute http or https URL to a public host", delivery.Recipient)
+	}
+
+	summary := json.RawMessage(delivery.Payload)
+	if len(summary) == 0 {
+		summary = json.RawMessage("null")
+	}
+	body, err := json.Marshal(webhookPayload{
+		ID:        delivery.ID,
+		AccountID: delivery.AccountID,
+		Subject:   delivery.Subject,
+		SentAt:    time.Now().UTC(),
+		Summary:   summary,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Recipient, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Notification-ID", delivery.ID)
+	req.Header.Set("X-Signature-Timestamp", timestamp)
+	req.Header.Set("X-Signature", "sha256="+n.sign(timestamp, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", delivery.Recipient, resp.Status)
+	}
+	return nil
+}
+
+func (n *WebhookNotifier) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, n.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func NewFileNotifier(dir string) (*FileNotifier, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileNotifier{dir: dir}, nil
+}
+
+func (n *FileNotifier) Channel() domain.Channel {
+	return domain.ChannelFile
+}
+
+// Notify writes to a temporary file first and renames it, so readers of the
+// spool never see half written messages.
+func (n *FileNotifier) Notify(_ context.Context, delivery domain.Delivery) error {
+	tmp, err := os.CreateTemp(n.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(deliveryMessage(delivery).Bytes()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(n.dir, delivery.ID+".eml"))
+}
+
+func deliveryMessage(delivery domain.Delivery) email.Message {
+	return email.Message{
+		To:      []mail.Address{{Address: delivery.Recipient}},
+		Subject: delivery.Subject,
+		HTML:    delivery.HTML,
+		Text:    delivery.Text,
+	}
+}
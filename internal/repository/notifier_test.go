@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	"github.com/castiglionimax/process-csv/pkg/email"
+	"github.com/castiglionimax/process-csv/pkg/email/emailtest"
+)
+
+// TestEmailNotifierConcurrent delivers from many goroutines through one
+// notifier, as the outbox dispatcher does. Run it with -race.
+func TestEmailNotifierConcurrent(t *testing.T) {
+	server := emailtest.NewServer(t, emailtest.Options{})
+	notifier := NewEmailNotifier(email.NewSMTPTransport(email.SMTPConfig{
+		Host: server.Host, Port: server.Port, Timeout: 5 * time.Second,
+		From: mail.Address{Address: "reports@example.com"},
+	}))
+
+	const deliveries = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, deliveries)
+	for i := 0; i < deliveries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- notifier.Notify(context.Background(), domain.Delivery{
+				ID:        fmt.Sprint(i),
+				Channel:   domain.ChannelEmail,
+				Recipient: fmt.Sprintf("customer-%d@example.com", i),
+				Subject:   fmt.Sprintf("Summary <%d>", i),
+				HTML:      fmt.Sprintf("<p>summary <%d></p>", i),
+				Text:      fmt.Sprintf("summary <%d>", i),
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Notify: %v", err)
+		}
+	}
+
+	messages := server.Messages()
+	if len(messages) != deliveries {
+		t.Fatalf("server received %d messages, want %d", len(messages), deliveries)
+	}
+	for _, received := range messages {
+		if len(received.To) != 1 {
+			t.Fatalf("message sent to %v, want one recipient", received.To)
+		}
+		var i int
+		if _, err := fmt.Sscanf(received.To[0], "customer-%d@example.com", &i); err != nil {
+			t.Fatalf("unexpected recipient %s", received.To[0])
+		}
+		parsed, err := mail.ReadMessage(strings.NewReader(received.Data))
+		if err != nil {
+			t.Fatalf("reading message: %v", err)
+		}
+		if to := parsed.Header.Get("To"); to != "<"+received.To[0]+">" {
+			t.Errorf("message for %s has To %q", received.To[0], to)
+		}
+		token := fmt.Sprintf("<%d>", i)
+		if subject := parsed.Header.Get("Subject"); subject != "Summary "+token {
+			t.Errorf("message for %s has subject %q", received.To[0], subject)
+		}
+		if strings.Count(received.Data, token) != 3 {
+			t.Errorf("message for %s does not carry its own bodies:\n%s", received.To[0], received.Data)
+		}
+	}
+}
+
+func TestWebhookNotifierRefusesInternalAddresses(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+	}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	notifier, err := NewWebhookNotifier(NewWebhookClient(5*time.Second), "secret")
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier: %v", err)
+	}
+	tests := []struct {
+		name      string
+		recipient string
+		err       string
+	}{
+		{name: "loopback address", recipient: server.URL, err: "not an absolute http or https URL to a public host"},
+		{name: "metadata address", recipient: "http://169.254.169.254/latest/meta-data", err: "not an absolute http or https URL to a public host"},
+		{name: "name resolving to loopback", recipient: "http://localhost:" + port + "/hook", err: "is not a public address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := notifier.Notify(context.Background(), domain.Delivery{ID: "1", Channel: domain.ChannelWebhook, Recipient: tt.recipient})
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Notify(%s) = %v, want an error containing %q", tt.recipient, err, tt.err)
+			}
+		})
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("the server received %d requests", n)
+	}
+}
+
+func TestDialPublic(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{address: "93.184.216.34:443", allowed: true},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443", allowed: true},
+		{address: "127.0.0.1:8080"},
+		{address: "[::1]:80"},
+		{address: "10.0.0.7:80"},
+		{address: "192.168.0.1:80"},
+		{address: "169.254.169.254:80"},
+		{address: "[fe80::1%eth0]:80"},
+		{address: "[::ffff:10.0.0.7]:80"},
+		{address: "localhost:80"},
+	}
+	for _, tt := range tests {
+		if err := dialPublic("tcp", tt.address, nil); (err == nil) != tt.allowed {
+			t.Errorf("dialPublic(%s) = %v, want allowed %v", tt.address, err, tt.allowed)
+		}
+	}
+}
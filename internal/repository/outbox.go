@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+const (
+	maxNotificationAttempts = 8
+	notificationBaseBackoff = 30 * time.Second
+	notificationMaxBackoff  = time.Hour
+	notificationLease       = 5 * time.Minute
+
+	insertNotification = "INSERT INTO notifications (id, account_id, channel, recipient, subject, html_body, text_body, payload, status, attempts, next_attempt_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?);"
+	getNotification    = "SELECT id, account_id, channel, recipient, subject, status, attempts, COALESCE(last_error, ''), created_at, updated_at FROM notifications WHERE id = ?;"
+	duePendingMessages = "SELECT id, account_id, channel, recipient, subject, html_body, text_body, payload, attempts FROM notifications WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED;"
+	leaseNotification  = "UPDATE notifications SET next_attempt_at = ? WHERE id = ?;"
+	markSent           = "UPDATE notifications SET status = ?, attempts = attempts + 1, last_error = NULL, updated_at = ? WHERE id = ?;"
+	markAttemptFailed  = "UPDATE notifications SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?, updated_at = ? WHERE id = ?;"
+)
+
+// enqueueNotification stores the delivery in the outbox, it is sent later by
+// the notification dispatcher.
+func (r Repository) enqueueNotification(ctx context.Context, delivery domain.Delivery) (string, error) {
+	id := uuid.New().String()
+	now := time.Now().UTC()
+	_, err := r.mysql.ExecContext(ctx, insertNotification, id, string(delivery.AccountID), delivery.Channel,
+		delivery.Recipient, delivery.Subject, delivery.HTML, delivery.Text, string(delivery.Payload),
+		domain.NotificationQueued, now, now, now)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (r Repository) GetNotification(ctx context.Context, id string) (domain.Notification, error) {
+	var notification domain.Notification
+	err := r.mysql.QueryRowContext(ctx, getNotification, id).Scan(&notification.ID, &notification.AccountID,
+		&notification.Channel, &notification.Recipient, &notification.Subject, &notification.Status,
+		&notification.Attempts, &notification.LastError, &notification.CreatedAt, &notification.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Notification{}, pkgError.NotFound("notification")
+	}
+	return notification, err
+}
+
+// ClaimNotifications returns up to batch queued deliveries that are due and
+// leases them, so other replicas skip them while they are being sent. A
+// delivery whose sender dies is picked up again once the lease expires.
+func (r Repository) ClaimNotifications(ctx context.Context, batch int) ([]domain.Delivery, error) {
+	tx, err := r.mysql.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	rows, err := tx.QueryContext(ctx, duePendingMessages, domain.NotificationQueued, now, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []domain.Delivery
+	for rows.Next() {
+		var (
+			delivery domain.Delivery
+			payload  string
+		)
+		if err = rows.Scan(&delivery.ID, &delivery.AccountID, &delivery.Channel, &delivery.Recipient,
+			&delivery.Subject, &delivery.HTML, &delivery.Text, &payload, &delivery.Attempts); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		delivery.Payload = []byte(payload)
+		pending = append(pending, delivery)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, delivery := range pending {
+		if _, err = tx.ExecContext(ctx, leaseNotification, now.Add(notificationLease), delivery.ID); err != nil {
+			return nil, err
+		}
+	}
+	return pending, tx.Commit()
+}
+
+// CompleteNotification records the outcome of a delivery attempt. Failed
+// attempts are retried with backoff until maxNotificationAttempts.
+func (r Repository) CompleteNotification(ctx context.Context, delivery domain.Delivery, errSend error) error {
+	now := time.Now().UTC()
+	if errSend == nil {
+		_, err := r.mysql.ExecContext(ctx, markSent, domain.NotificationSent, now, delivery.ID)
+		return err
+	}
+
+	status := domain.NotificationQueued
+	if delivery.Attempts+1 >= maxNotificationAttempts {
+		status = domain.NotificationFailed
+	}
+	_, err := r.mysql.ExecContext(ctx, markAttemptFailed, status, errSend.Error(),
+		now.Add(backoff(delivery.Attempts+1)), now, delivery.ID)
+	return err
+}
+
+// backoff doubles the wait after every failed attempt, up to an hour.
+func backoff(attempts int) time.Duration {
+	wait := notificationBaseBackoff
+	for i := 1; i < attempts && wait < notificationMaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > notificationMaxBackoff {
+		wait = notificationMaxBackoff
+	}
+	return wait
+}
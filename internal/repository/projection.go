@@ -3,11 +3,13 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/go-sql-driver/mysql"
 	"time"
 
 	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
 )
 
 type (
@@ -17,10 +19,38 @@ type (
 )
 
 const (
-	insertAccount       = "INSERT INTO accounts (id, name, email, amount, last_updated) VALUES (?, ?, ?, ?,?)"
+	insertAccount       = "INSERT INTO accounts (id, name, email, locale, timezone, status, channels, webhook_url, overdraft_policy, overdraft_limit, amount, last_updated) VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?,?)"
+	updateAccountPrefs  = "UPDATE accounts SET channels = ?, webhook_url = NULLIF(?, ''), last_updated = ? WHERE id = ?;"
+	updateAccountPolicy = "UPDATE accounts SET overdraft_policy = ?, overdraft_limit = ?, last_updated = ? WHERE id = ?;"
 	UpdateAccountAmount = "UPDATE accounts SET amount = amount + ?, last_updated= ? WHERE id = ?;"
 
-	updateSummary = "INSERT INTO summaries (account_id, period, credit, credit_qty, debit, debit_qty,last_updated) VALUES (?, ?, ?, ?,?,?,?) ON DUPLICATE KEY UPDATE credit = credit + VALUES(credit), credit_qty = credit_qty + VALUES(credit_qty), debit = debit + VALUES(debit), debit_qty = debit_qty + VALUES(debit_qty), last_updated =  VALUES(last_updated);"
+	lockAccount = "SELECT timezone FROM accounts WHERE id = ? FOR UPDATE;"
+
+	// the balances from the period before start on
+	selectBalances = "(SELECT period, period_start, opening_balance, closing_balance FROM summaries WHERE account_id = ? AND granularity = ? AND period_start < ? ORDER BY period_start DESC LIMIT 1) " +
+		"UNION ALL (SELECT period, period_start, opening_balance, closing_balance FROM summaries WHERE account_id = ? AND granularity = ? AND period_start >= ?) ORDER BY period_start;"
+	updateBalances = "UPDATE summaries SET opening_balance = ?, closing_balance = ? WHERE account_id = ? AND granularity = ? AND period = ?;"
+
+	// LEAST and GREATEST return NULL when any argument is NULL, the COALESCE
+	// keeps whichever side is set. The balances are the ones carryBalance
+	// gives the period.
+	updateSummary = "INSERT INTO summaries (account_id, granularity, period, period_start, credit, credit_qty, debit, debit_qty, transfer_in, transfer_in_qty, transfer_out, transfer_out_qty, min_credit, max_credit, min_debit, max_debit, opening_balance, closing_balance, last_updated) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) " +
+		"ON DUPLICATE KEY UPDATE credit = credit + VALUES(credit), credit_qty = credit_qty + VALUES(credit_qty), debit = debit + VALUES(debit), debit_qty = debit_qty + VALUES(debit_qty), " +
+		"transfer_in = transfer_in + VALUES(transfer_in), transfer_in_qty = transfer_in_qty + VALUES(transfer_in_qty), transfer_out = transfer_out + VALUES(transfer_out), transfer_out_qty = transfer_out_qty + VALUES(transfer_out_qty), " +
+		"min_credit = COALESCE(LEAST(min_credit, VALUES(min_credit)), min_credit, VALUES(min_credit)), max_credit = COALESCE(GREATEST(max_credit, VALUES(max_credit)), max_credit, VALUES(max_credit)), " +
+		"min_debit = COALESCE(LEAST(min_debit, VALUES(min_debit)), min_debit, VALUES(min_debit)), max_debit = COALESCE(GREATEST(max_debit, VALUES(max_debit)), max_debit, VALUES(max_debit)), " +
+		"opening_balance = VALUES(opening_balance), closing_balance = VALUES(closing_balance), last_updated = VALUES(last_updated);"
+
+	// Reversals cannot restore min and max, they keep the sizes seen.
+	reverseSummary = "UPDATE summaries SET credit = credit - ?, credit_qty = credit_qty - ?, debit = debit - ?, debit_qty = debit_qty - ?, " +
+		"transfer_in = transfer_in - ?, transfer_in_qty = transfer_in_qty - ?, transfer_out = transfer_out - ?, transfer_out_qty = transfer_out_qty - ?, " +
+		"last_updated = ? WHERE account_id = ? AND granularity = ? AND period = ?;"
+	reverseCategorySummary = "UPDATE category_summaries SET credit = credit - ?, credit_qty = credit_qty - ?, debit = debit - ?, debit_qty = debit_qty - ?, last_updated = ? WHERE account_id = ? AND granularity = ? AND period = ? AND category = ?;"
+
+	insertTxCategory = "INSERT INTO transaction_categories (event_id, account_id, category) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE category = VALUES(category);"
+	selectTxCategory = "SELECT category FROM transaction_categories WHERE event_id = ?;"
+
+	updateCategorySummary = "INSERT INTO category_summaries (account_id, granularity, period, period_start, category, credit, credit_qty, debit, debit_qty, last_updated) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE credit = credit + VALUES(credit), credit_qty = credit_qty + VALUES(credit_qty), debit = debit + VALUES(debit), debit_qty = debit_qty + VALUES(debit_qty), last_updated = VALUES(last_updated);"
 )
 
 func NewProjection(db *sql.DB) *ProjectionAccount {
@@ -33,13 +63,26 @@ func (p ProjectionAccount) CreateAccount(ctx context.Context, account domain.Acc
 		return err
 	}
 
-	_, err = insertStatement.ExecContext(ctx, account.ID, account.Name, account.Email, 0, time.Now().UTC())
+	_, err = insertStatement.ExecContext(ctx, account.ID, account.Name, account.Email, account.Locale, account.Timezone, account.Status,
+		formatChannels(account.Channels), account.WebhookURL, account.OverdraftPolicy, account.OverdraftLimit, 0, time.Now().UTC())
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func (p ProjectionAccount) UpdatePreferences(ctx context.Context, preferences domain.Preferences) error {
+	_, err := p.mysql.ExecContext(ctx, updateAccountPrefs, formatChannels(preferences.Channels),
+		preferences.WebhookURL, time.Now().UTC(), preferences.AccountID)
+	return err
+}
+
+func (p ProjectionAccount) UpdatePolicy(ctx context.Context, policy domain.Policy) error {
+	_, err := p.mysql.ExecContext(ctx, updateAccountPolicy, policy.OverdraftPolicy, policy.OverdraftLimit,
+		time.Now().UTC(), policy.AccountID)
+	return err
+}
+
 func (p ProjectionAccount) RegisterTransaction(ctx context.Context, tx domain.Transaction) error {
 	insertStatement, err := p.mysql.Prepare(UpdateAccountAmount)
 	if err != nil {
@@ -59,19 +102,221 @@ func (p ProjectionAccount) RegisterTransaction(ctx context.Context, tx domain.Tr
 	return nil
 }
 
-func (p ProjectionAccount) RegisterSummary(ctx context.Context, tx domain.Transaction) error {
-	insertStatement, err := p.mysql.Prepare(updateSummary)
+// RegisterSummary adds the transaction to the summary of its period, at
+// every granularity, and keeps the period balances running. Periods are the
+// calendar ones of the account timezone. Transactions may
+// arrive for past periods, so the balances of every later period are
+// corrected too. The movement is added to the summary of its category as
+// well, tx.Category must already be set, and kept under eventID for the
+// reversal. The account row is locked to apply the changes of one account in
+// order.
+func (p ProjectionAccount) RegisterSummary(ctx context.Context, eventID string, tx domain.Transaction) error {
+	sqlTx, err := p.mysql.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	f := func(current time.Time) string {
-		year, month, _ := current.Date()
-		return fmt.Sprintf("%d %s", year, month)
+	defer sqlTx.Rollback()
+
+	var account domain.Account
+	if err = sqlTx.QueryRowContext(ctx, lockAccount, tx.AccountID).Scan(&account.Timezone); err != nil {
+		return err
+	}
+
+	m := newMovement(tx)
+	date := tx.Date.In(account.Location())
+	now := time.Now().UTC()
+	for _, granularity := range domain.Granularities {
+		period, periodStart := granularity.Key(date), granularity.Start(date)
+
+		balances, err := selectPeriodBalances(ctx, sqlTx, tx.AccountID, granularity, periodStart)
+		if err != nil {
+			return err
+		}
+		changed := carryBalance(balances, period, periodStart, tx.Amount)
+
+		_, err = sqlTx.ExecContext(ctx, updateSummary, tx.AccountID, granularity, period, periodStart,
+			m.credit, m.creditQty, m.debit, m.debitQty, m.transferIn, m.transferInQty, m.transferOut, m.transferOutQty,
+			m.minCredit, m.maxCredit, m.minDebit, m.maxDebit, changed[0].opening, changed[0].closing, now)
+		if err != nil {
+			return err
+		}
+
+		// transfers are not spending, they are left out of the categories
+		if !m.transfer() {
+			_, err = sqlTx.ExecContext(ctx, updateCategorySummary, tx.AccountID, granularity, period, periodStart,
+				tx.Category, m.credit, m.creditQty, m.debit, m.debitQty, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err = updatePeriodBalances(ctx, sqlTx, tx.AccountID, granularity, changed[1:]); err != nil {
+			return err
+		}
+	}
+
+	if !m.transfer() {
+		if _, err = sqlTx.ExecContext(ctx, insertTxCategory, eventID, tx.AccountID, tx.Category); err != nil {
+			return err
+		}
+	}
+	return sqlTx.Commit()
+}
+
+// TransactionCategory returns the category the transaction of the event was
+// added to the summaries under.
+func (p ProjectionAccount) TransactionCategory(ctx context.Context, eventID string) (string, error) {
+	var category string
+	err := p.mysql.QueryRowContext(ctx, selectTxCategory, eventID).Scan(&category)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", pkgError.NotFound("transaction category")
+	}
+	return category, err
+}
+
+// ReverseSummary takes the transaction, amount and count, out of the summary
+// of its period at every granularity and out of the balances of the periods
+// after it. It fails while the transaction was not registered yet, so the
+// reversal is retried. tx.Category must be the category the transaction was
+// registered with.
+func (p ProjectionAccount) ReverseSummary(ctx context.Context, tx domain.Transaction) error {
+	sqlTx, err := p.mysql.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback()
+
+	var account domain.Account
+	if err = sqlTx.QueryRowContext(ctx, lockAccount, tx.AccountID).Scan(&account.Timezone); err != nil {
+		return err
 	}
-	if tx.Amount > 0 {
-		_, err = insertStatement.ExecContext(ctx, tx.AccountID, f(tx.Date), tx.Amount, 1, 0, 0, time.Now().UTC())
+
+	m := newMovement(tx)
+	date := tx.Date.In(account.Location())
+	now := time.Now().UTC()
+	for _, granularity := range domain.Granularities {
+		period, periodStart := granularity.Key(date), granularity.Start(date)
+
+		result, err := sqlTx.ExecContext(ctx, reverseSummary, m.credit, m.creditQty, m.debit, m.debitQty,
+			m.transferIn, m.transferInQty, m.transferOut, m.transferOutQty, now, tx.AccountID, granularity, period)
+		if err != nil {
+			return err
+		}
+		if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+			return fmt.Errorf("summary %s %s of account %s not registered yet", granularity, period, tx.AccountID)
+		}
+
+		if !m.transfer() {
+			if _, err = sqlTx.ExecContext(ctx, reverseCategorySummary, m.credit, m.creditQty, m.debit, m.debitQty, now,
+				tx.AccountID, granularity, period, tx.Category); err != nil {
+				return err
+			}
+		}
+
+		balances, err := selectPeriodBalances(ctx, sqlTx, tx.AccountID, granularity, periodStart)
+		if err != nil {
+			return err
+		}
+		if err = updatePeriodBalances(ctx, sqlTx, tx.AccountID, granularity, carryBalance(balances, period, periodStart, -tx.Amount)); err != nil {
+			return err
+		}
+	}
+	return sqlTx.Commit()
+}
+
+// periodBalance is the running balance of one summary period.
+type periodBalance struct {
+	period           string
+	start            time.Time
+	opening, closing float64
+}
+
+// carryBalance moves amount into the balance of the period starting at
+// start. balances are the ones of the account at one granularity, sorted by
+// start, from the last period before it on. A missing period opens with the
+// closing balance of the period before it, or with 0 when it is the first
+// one. Every later period moves by amount as well, transactions may arrive
+// for past periods. It returns the balances changed, the one of the period
+// first.
+func carryBalance(balances []periodBalance, period string, start time.Time, amount float64) []periodBalance {
+	var (
+		opening float64
+		changed []periodBalance
+	)
+	i := 0
+	for ; i < len(balances) && balances[i].start.Before(start); i++ {
+		opening = balances[i].closing
+	}
+	if i < len(balances) && balances[i].start.Equal(start) {
+		current := balances[i]
+		current.closing += amount
+		changed = append(changed, current)
+		i++
 	} else {
-		_, err = insertStatement.ExecContext(ctx, tx.AccountID, f(tx.Date), 0, 0, tx.Amount, 1, time.Now().UTC())
+		changed = append(changed, periodBalance{period: period, start: start, opening: opening, closing: opening + amount})
 	}
-	return err
+	for _, later := range balances[i:] {
+		later.opening += amount
+		later.closing += amount
+		changed = append(changed, later)
+	}
+	return changed
+}
+
+func selectPeriodBalances(ctx context.Context, sqlTx *sql.Tx, accountID domain.AccountID, granularity domain.Granularity, start time.Time) ([]periodBalance, error) {
+	rows, err := sqlTx.QueryContext(ctx, selectBalances, accountID, granularity, start, accountID, granularity, start)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var balances []periodBalance
+	for rows.Next() {
+		var balance periodBalance
+		if err = rows.Scan(&balance.period, &balance.start, &balance.opening, &balance.closing); err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance)
+	}
+	return balances, rows.Err()
+}
+
+func updatePeriodBalances(ctx context.Context, sqlTx *sql.Tx, accountID domain.AccountID, granularity domain.Granularity, balances []periodBalance) error {
+	for _, balance := range balances {
+		if _, err := sqlTx.ExecContext(ctx, updateBalances, balance.opening, balance.closing, accountID, granularity, balance.period); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// movement splits a transaction into the summary columns it moves. Transfers
+// between accounts only move the transfer columns. min and max keep
+// transaction sizes, debits are stored without sign, nil leaves them as they
+// are.
+type movement struct {
+	credit, debit, transferIn, transferOut             float64
+	creditQty, debitQty, transferInQty, transferOutQty int
+	minCredit, maxCredit, minDebit, maxDebit           any
+}
+
+func newMovement(tx domain.Transaction) movement {
+	var m movement
+	switch {
+	case tx.Type == domain.TransactionTransfer && tx.Amount > 0:
+		m.transferIn, m.transferInQty = tx.Amount, 1
+	case tx.Type == domain.TransactionTransfer:
+		m.transferOut, m.transferOutQty = tx.Amount, 1
+	case tx.Amount > 0:
+		m.credit, m.creditQty = tx.Amount, 1
+		m.minCredit, m.maxCredit = tx.Amount, tx.Amount
+	default:
+		m.debit, m.debitQty = tx.Amount, 1
+		m.minDebit, m.maxDebit = -tx.Amount, -tx.Amount
+	}
+	return m
+}
+
+func (m movement) transfer() bool {
+	return m.transferInQty+m.transferOutQty > 0
 }
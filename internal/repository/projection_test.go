@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+func TestCarryBalance(t *testing.T) {
+	month := func(m time.Month) time.Time { return time.Date(2023, m, 1, 0, 0, 0, 0, time.UTC) }
+	balance := func(m time.Month, opening, closing float64) periodBalance {
+		return periodBalance{period: month(m).Format("2006-01"), start: month(m), opening: opening, closing: closing}
+	}
+
+	tests := []struct {
+		name     string
+		balances []periodBalance
+		start    time.Time
+		amount   float64
+		want     []periodBalance
+	}{
+		{
+			name:   "first period",
+			start:  month(10),
+			amount: 100,
+			want:   []periodBalance{balance(10, 0, 100)},
+		},
+		{
+			name:     "first period with later ones",
+			balances: []periodBalance{balance(10, 0, 100), balance(11, 100, 70)},
+			start:    month(9),
+			amount:   20,
+			want:     []periodBalance{balance(9, 0, 20), balance(10, 20, 120), balance(11, 120, 90)},
+		},
+		{
+			name:     "new period after the last one",
+			balances: []periodBalance{balance(10, 0, 100)},
+			start:    month(11),
+			amount:   -30,
+			want:     []periodBalance{balance(11, 100, 70)},
+		},
+		{
+			name:     "new period after a gap",
+			balances: []periodBalance{balance(8, 0, 100)},
+			start:    month(11),
+			amount:   5,
+			want:     []periodBalance{balance(11, 100, 105)},
+		},
+		{
+			name:     "existing period",
+			balances: []periodBalance{balance(9, 0, 50), balance(10, 50, 100)},
+			start:    month(10),
+			amount:   -30,
+			want:     []periodBalance{balance(10, 50, 70)},
+		},
+		{
+			name:     "earlier existing period",
+			balances: []periodBalance{balance(9, 0, 50), balance(10, 50, 100), balance(11, 100, 70), balance(12, 70, 90)},
+			start:    month(10),
+			amount:   25,
+			want:     []periodBalance{balance(10, 50, 125), balance(11, 125, 95), balance(12, 95, 115)},
+		},
+		{
+			name:     "earlier missing period",
+			balances: []periodBalance{balance(9, 0, 50), balance(11, 50, 20)},
+			start:    month(10),
+			amount:   -10,
+			want:     []periodBalance{balance(10, 50, 40), balance(11, 40, 10)},
+		},
+		{
+			name:     "reversal",
+			balances: []periodBalance{balance(9, 0, 50), balance(10, 50, 100), balance(11, 100, 70)},
+			start:    month(10),
+			amount:   -40,
+			want:     []periodBalance{balance(10, 50, 60), balance(11, 60, 30)},
+		},
+		{
+			name:     "reversal in the first period",
+			balances: []periodBalance{balance(10, 0, 100), balance(11, 100, 70)},
+			start:    month(10),
+			amount:   -100,
+			want:     []periodBalance{balance(10, 0, 0), balance(11, 0, -30)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := carryBalance(tt.balances, tt.start.Format("2006-01"), tt.start, tt.amount)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("carryBalance\n got %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCarryBalanceRoundTrip(t *testing.T) {
+	// a transaction registered in an earlier period and then reversed leaves
+	// the balances as they were
+	start := time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC)
+	balances := []periodBalance{
+		{period: "2023-W39", start: start.AddDate(0, 0, -7), opening: 0, closing: 10},
+		{period: "2023-W40", start: start, opening: 10, closing: 30},
+		{period: "2023-W41", start: start.AddDate(0, 0, 7), opening: 30, closing: 25},
+	}
+	registered := carryBalance(balances, "2023-W40", start, -12.5)
+	reversed := carryBalance(append(balances[:1:1], registered...), "2023-W40", start, 12.5)
+	if fmt.Sprint(reversed) != fmt.Sprint(balances[1:]) {
+		t.Fatalf("after the reversal %v, want %v", reversed, balances[1:])
+	}
+}
+
+func TestNewMovement(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   domain.Transaction
+		want movement
+	}{
+		{name: "credit", tx: domain.Transaction{Amount: 80}, want: movement{credit: 80, creditQty: 1, minCredit: 80.0, maxCredit: 80.0}},
+		{name: "debit", tx: domain.Transaction{Amount: -30}, want: movement{debit: -30, debitQty: 1, minDebit: 30.0, maxDebit: 30.0}},
+		{name: "transfer in", tx: domain.Transaction{Amount: 50, Type: domain.TransactionTransfer}, want: movement{transferIn: 50, transferInQty: 1}},
+		{name: "transfer out", tx: domain.Transaction{Amount: -50, Type: domain.TransactionTransfer}, want: movement{transferOut: -50, transferOutQty: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newMovement(tt.tx)
+			if got != tt.want {
+				t.Fatalf("newMovement = %+v, want %+v", got, tt.want)
+			}
+			if transfer := tt.tx.Type == domain.TransactionTransfer; got.transfer() != transfer {
+				t.Fatalf("transfer() = %v, want %v", got.transfer(), transfer)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+const (
+	listActiveAccounts = "SELECT id, timezone FROM accounts WHERE status = ?;"
+
+	reserveDelivery = "INSERT IGNORE INTO summary_deliveries (account_id, period, sent_at) VALUES (?, ?, ?);"
+	releaseDelivery = "DELETE FROM summary_deliveries WHERE account_id = ? AND period = ?;"
+
+	getLock     = "SELECT COALESCE(GET_LOCK(?, 0), 0);"
+	releaseLock = "SELECT RELEASE_LOCK(?);"
+)
+
+func (r Repository) ListActiveAccounts(ctx context.Context) ([]domain.Account, error) {
+	rows, err := r.mysql.QueryContext(ctx, listActiveAccounts, domain.AccountActive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []domain.Account
+	for rows.Next() {
+		var account domain.Account
+		if err = rows.Scan(&account.ID, &account.Timezone); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// ReserveSummaryDelivery records that the summary of period is being sent to
+// the account. It returns false when it was already recorded.
+func (r Repository) ReserveSummaryDelivery(ctx context.Context, accountID domain.AccountID, period string) (bool, error) {
+	result, err := r.mysql.ExecContext(ctx, reserveDelivery, string(accountID), period, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r Repository) ReleaseSummaryDelivery(ctx context.Context, accountID domain.AccountID, period string) error {
+	_, err := r.mysql.ExecContext(ctx, releaseDelivery, string(accountID), period)
+	return err
+}
+
+// AcquireLock takes a MySQL named lock without waiting. Named locks belong to
+// a session, so a dedicated connection is held until release is called.
+func (r Repository) AcquireLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := r.mysql.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired int
+	if err = conn.QueryRowContext(ctx, getLock, name).Scan(&acquired); err != nil || acquired != 1 {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), releaseLock, name); err != nil {
+			log.Printf("releasing lock %s: %v", name, err)
+		}
+		_ = conn.Close()
+	}, true, nil
+}
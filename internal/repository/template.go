@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	"github.com/castiglionimax/process-csv/pkg/i18n"
+)
+
+const (
+	summaryHTMLTemplate    = "summary.html.tmpl"
+	summaryTextTemplate    = "summary.txt.tmpl"
+	summarySubjectTemplate = "summary.subject.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+type (
+	// Renderer turns summary views into email content. Templates are embedded
+	// in the binary; any file with the same name found in the override
+	// directory takes precedence. Every render works on its own clone of the
+	// parsed templates, so a Renderer is safe for concurrent use.
+	Renderer struct {
+		html *htmltemplate.Template
+		text *texttemplate.Template
+	}
+
+	renderedSummary struct {
+		Subject, HTML, Text string
+	}
+)
+
+func NewRenderer(dir string) (*Renderer, error) {
+	embedded, err := fs.Sub(embeddedTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	load := func(name string) (string, error) {
+		if dir != "" {
+			content, err := os.ReadFile(dir + string(os.PathSeparator) + name)
+			if err == nil {
+				return string(content), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+		content, err := fs.ReadFile(embedded, name)
+		return string(content), err
+	}
+
+	funcs := localizedFuncs(i18n.For(i18n.DefaultLocale))
+
+	content, err := load(summaryHTMLTemplate)
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New(summaryHTMLTemplate).Funcs(funcs).Parse(content)
+	if err != nil {
+		return nil, err
+	}
+
+	text := texttemplate.New("").Funcs(funcs)
+	for _, name := range []string{summaryTextTemplate, summarySubjectTemplate} {
+		if content, err = load(name); err != nil {
+			return nil, err
+		}
+		if _, err = text.New(name).Parse(content); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Renderer{html: html, text: text}, nil
+}
+
+func (r *Renderer) HTML(view summaryView) (string, error) {
+	// html/template refuses to clone a template that was already executed, so
+	// the parsed templates are never run directly, only their clones.
+	html, err := r.html.Clone()
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err = html.Funcs(localizedFuncs(i18n.For(view.Locale))).Execute(&buf, view); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (r *Renderer) Summary(view summaryView) (renderedSummary, error) {
+	var (
+		rendered renderedSummary
+		buf      bytes.Buffer
+	)
+	text, err := r.text.Clone()
+	if err != nil {
+		return rendered, err
+	}
+	text.Funcs(localizedFuncs(i18n.For(view.Locale)))
+
+	if err = text.ExecuteTemplate(&buf, summarySubjectTemplate, view); err != nil {
+		return rendered, err
+	}
+	rendered.Subject = strings.TrimSpace(buf.String())
+
+	buf.Reset()
+	if err = text.ExecuteTemplate(&buf, summaryTextTemplate, view); err != nil {
+		return rendered, err
+	}
+	rendered.Text = buf.String()
+
+	rendered.HTML, err = r.HTML(view)
+	return rendered, err
+}
+
+func localizedFuncs(l i18n.Localizer) map[string]any {
+	return map[string]any{
+		"t":      l.T,
+		"money":  l.Money,
+		"date":   l.Date,
+		"period": func(period string) string { return localizedPeriod(l, period) },
+		"reason": func(violation domain.Violation) string {
+			key := "violation." + violation.Policy
+			if reason := l.T(key); reason != key {
+				return reason
+			}
+			return violation.Reason
+		},
+		"category": func(category string) string {
+			if category == domain.Uncategorized {
+				return l.T("summary.uncategorized")
+			}
+			return category
+		},
+	}
+}
+
+func localizedPeriod(l i18n.Localizer, period string) string {
+	granularity, start, err := domain.ParsePeriod(period)
+	if err != nil {
+		return period
+	}
+	switch granularity {
+	case domain.GranularityDay:
+		return l.Date(start)
+	case domain.GranularityWeek:
+		year, week := start.ISOWeek()
+		return l.T("period.week", week, year)
+	case domain.GranularityQuarter:
+		return l.T("period.quarter", (int(start.Month())+2)/3, start.Year())
+	case domain.GranularityYear:
+		return strconv.Itoa(start.Year())
+	default:
+		return l.Period(start.Year(), start.Month())
+	}
+}
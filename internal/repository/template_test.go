@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// goldenSummaryView is a report covering every section of the templates.
+// The account name and a category carry markup the HTML must escape.
+func goldenSummaryView(locale string) summaryView {
+	flagged := time.Date(2023, 10, 14, 18, 30, 0, 0, time.UTC)
+	return summaryView{
+		SummaryReport: domain.SummaryReport{
+			AccountID:      "4a1f9c2e-0b7d-4e55-9a43-7d7c1e2b8f10",
+			Granularity:    domain.GranularityMonth,
+			Balance:        1534.5,
+			OpeningBalance: 1000,
+			ClosingBalance: 1534.5,
+			Periods: []domain.PeriodStatistics{
+				{Period: "2023-09", Statistics: domain.Statistics{
+					Movements: 3, Credit: 1200, CreditQty: 1, Debit: -450.25, DebitQty: 2, NetFlow: 749.75,
+					AverageCredit: 1200, AverageDebit: -225.125, MinCredit: 1200, MaxCredit: 1200, MinDebit: 50.25, MaxDebit: 400,
+				}, OpeningBalance: 1000, ClosingBalance: 1749.75},
+				{Period: "2023-10", Statistics: domain.Statistics{
+					Movements: 2, Debit: -65.25, DebitQty: 1, TransferOut: -150, TransferOutQty: 1, NetFlow: -215.25,
+					AverageDebit: -65.25, MinDebit: 65.25, MaxDebit: 65.25,
+				}, OpeningBalance: 1749.75, ClosingBalance: 1534.5},
+			},
+			Totals: domain.Statistics{
+				Movements: 5, Credit: 1200, CreditQty: 1, Debit: -515.5, DebitQty: 3, TransferOut: -150, TransferOutQty: 1,
+				NetFlow: 534.5, AverageCredit: 1200, AverageDebit: -171.833, MinCredit: 1200, MaxCredit: 1200, MinDebit: 50.25, MaxDebit: 400,
+			},
+			Categories: []domain.CategorySpending{
+				{Category: "rent", Debit: -400, DebitQty: 1},
+				{Category: "<b>food</b> & drinks", Debit: -115.5, DebitQty: 2},
+				{Category: domain.Uncategorized, Credit: 1200, CreditQty: 1},
+			},
+			Flags: []domain.Flag{{Violation: domain.Violation{
+				Transaction: domain.Transaction{AccountID: "4a1f9c2e-0b7d-4e55-9a43-7d7c1e2b8f10", Date: flagged, Amount: -400},
+				Policy:      "large_amount",
+				Reason:      "8.0 times the average amount",
+			}}},
+		},
+		AccountName: `Juan <script>alert("hi")</script>`,
+		Locale:      locale,
+		GeneratedAt: time.Date(2023, 11, 1, 8, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestRendererGolden(t *testing.T) {
+	renderer, err := NewRenderer("")
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+	for _, locale := range []string{"en", "es"} {
+		t.Run(locale, func(t *testing.T) {
+			rendered, err := renderer.Summary(goldenSummaryView(locale))
+			if err != nil {
+				t.Fatalf("Summary: %v", err)
+			}
+			assertGolden(t, "summary."+locale+".subject.golden", rendered.Subject)
+			assertGolden(t, "summary."+locale+".txt.golden", rendered.Text)
+			assertGolden(t, "summary."+locale+".html.golden", rendered.HTML)
+		})
+	}
+}
+
+func TestRendererEscapesHTML(t *testing.T) {
+	renderer, err := NewRenderer("")
+	if err != nil {
+		t.Fatalf("NewRenderer: %v", err)
+	}
+	rendered, err := renderer.Summary(goldenSummaryView("en"))
+	if err != nil {
+		t.Fatalf("Summary: %v", err)
+	}
+	for _, raw := range []string{"<script>", "<b>food</b>"} {
+		if strings.Contains(rendered.HTML, raw) {
+			t.Errorf("HTML body contains %q unescaped", raw)
+		}
+	}
+	for _, escaped := range []string{"&lt;script&gt;", "&lt;b&gt;food&lt;/b&gt; &amp; drinks"} {
+		if !strings.Contains(rendered.HTML, escaped) {
+			t.Errorf("HTML body does not contain %q", escaped)
+		}
+	}
+	// the text body is plain text, it shows the name as given
+	if !strings.Contains(rendered.Text, `Juan <script>alert("hi")</script>`) {
+		t.Errorf("text body does not contain the account name as given")
+	}
+}
+
+// assertGolden compares got with testdata/name, rewritten when the tests
+// run with -update.
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file, run the tests with -update to create it: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the golden file\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+// anomalyLookback is how far before the transactions being processed the
+// stored ones are handed to the anomaly rules.
+const anomalyLookback = 24 * time.Hour
+
+type (
+	// AnomalyRule looks for suspicious movements among the transactions of one
+	// account being processed. Flagged transactions are still stored.
+	AnomalyRule interface {
+		Name() string
+		Check(input AnomalyInput) []domain.Violation
+	}
+
+	// AnomalyInput is what a rule knows about one account: the sizes of its
+	// stored transactions, the ones stored from anomalyLookback before the
+	// first transaction being processed, and the transactions being processed
+	// in date order. Transactions sent again are left out of the ones being
+	// processed, they are only counted by their stored copy.
+	AnomalyInput struct {
+		AccountID    domain.AccountID
+		Stats        domain.AmountStats
+		Stored       []domain.Transaction
+		Transactions []domain.Transaction
+	}
+
+	// LargeAmountRule flags transactions more than Factor times the average
+	// size of the account and more than Deviations standard deviations above
+	// it, once the account has MinHistory transactions stored. Accounts whose
+	// sizes vary a lot need larger amounts to be flagged.
+	LargeAmountRule struct {
+		Factor     float64
+		Deviations float64
+		MinHistory int
+	}
+
+	// BurstRule flags transactions arriving when the account already has Max
+	// transactions, stored or being processed, in the Window before them.
+	BurstRule struct {
+		Window time.Duration
+		Max    int
+	}
+
+	// DuplicateRule flags transactions with the amount and the time of another
+	// one of the account.
+	DuplicateRule struct{}
+)
+
+// DefaultAnomalyRules returns the rules used unless others are set.
+func DefaultAnomalyRules() []AnomalyRule {
+	return []AnomalyRule{
+		LargeAmountRule{Factor: 5, Deviations: 3, MinHistory: 10},
+		BurstRule{Window: 10 * time.Minute, Max: 5},
+		DuplicateRule{},
+	}
+}
+
+// SetAnomalyRules replaces the rules run while processing files, none
+// disables them.
+func (s *Service) SetAnomalyRules(rules ...AnomalyRule) {
+	s.anomalyRules = rules
+}
+
+func (LargeAmountRule) Name() string { return "large_amount" }
+
+func (r LargeAmountRule) Check(input AnomalyInput) []domain.Violation {
+	if input.Stats.Count < r.MinHistory || input.Stats.Mean == 0 {
+		return nil
+	}
+	threshold := math.Max(r.Factor*input.Stats.Mean, input.Stats.Mean+r.Deviations*input.Stats.StdDev)
+	var flags []domain.Violation
+	for _, tx := range input.Transactions {
+		if size := math.Abs(tx.Amount); size > threshold {
+			flags = append(flags, domain.Violation{
+				Transaction: tx,
+				Policy:      r.Name(),
+				Reason:      fmt.Sprintf("%.1f times the average amount", size/input.Stats.Mean),
+			})
+		}
+	}
+	return flags
+}
+
+func (BurstRule) Name() string { return "burst" }
+
+func (r BurstRule) Check(input AnomalyInput) []domain.Violation {
+	dates := make([]time.Time, 0, len(input.Stored)+len(input.Transactions))
+	for _, tx := range input.Stored {
+		dates = append(dates, tx.Date)
+	}
+	for _, tx := range input.Transactions {
+		dates = append(dates, tx.Date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	var flags []domain.Violation
+	for _, tx := range input.Transactions {
+		from := sort.Search(len(dates), func(i int) bool { return !dates[i].Before(tx.Date.Add(-r.Window)) })
+		to := sort.Search(len(dates), func(i int) bool { return dates[i].After(tx.Date) })
+		if count := to - from; count > r.Max {
+			flags = append(flags, domain.Violation{
+				Transaction: tx,
+				Policy:      r.Name(),
+				Reason:      fmt.Sprintf("%d transactions in %s", count, r.Window),
+			})
+		}
+	}
+	return flags
+}
+
+func (DuplicateRule) Name() string { return "duplicate" }
+
+func (r DuplicateRule) Check(input AnomalyInput) []domain.Violation {
+	type key struct {
+		date   int64
+		amount float64
+	}
+	seen := make(map[key]bool, len(input.Stored)+len(input.Transactions))
+	for _, tx := range input.Stored {
+		seen[key{tx.Date.UnixNano(), tx.Amount}] = true
+	}
+
+	var flags []domain.Violation
+	for _, tx := range input.Transactions {
+		k := key{tx.Date.UnixNano(), tx.Amount}
+		if seen[k] {
+			flags = append(flags, domain.Violation{
+				Transaction: tx,
+				Policy:      r.Name(),
+				Reason:      "same amount and time as another transaction",
+			})
+		}
+		seen[k] = true
+	}
+	return flags
+}
+
+// detectAnomalies runs the anomaly rules over the transactions, sorted by
+// date, of every account.
+func (s Service) detectAnomalies(ctx context.Context, transactions []domain.Transaction) ([]domain.Violation, error) {
+	if len(s.anomalyRules) == 0 {
+		return nil, nil
+	}
+	byAccount := make(map[domain.AccountID][]domain.Transaction)
+	var accounts []domain.AccountID
+	for _, tx := range transactions {
+		if _, ok := byAccount[tx.AccountID]; !ok {
+			accounts = append(accounts, tx.AccountID)
+		}
+		byAccount[tx.AccountID] = append(byAccount[tx.AccountID], tx)
+	}
+
+	var flags []domain.Violation
+	for _, accountID := range accounts {
+		batch, err := s.unsent(ctx, byAccount[accountID])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		stats, err := s.repository.AmountStats(ctx, accountID)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := s.repository.TransactionsBetween(ctx, accountID,
+			batch[0].Date.Add(-anomalyLookback), batch[len(batch)-1].Date)
+		if err != nil {
+			return nil, err
+		}
+
+		input := AnomalyInput{AccountID: accountID, Stats: stats, Stored: stored, Transactions: batch}
+		for _, rule := range s.anomalyRules {
+			flags = append(flags, rule.Check(input)...)
+		}
+	}
+	return flags, nil
+}
+
+// unsent leaves out the transactions already stored or repeated in the batch.
+func (s Service) unsent(ctx context.Context, transactions []domain.Transaction) ([]domain.Transaction, error) {
+	resent, err := s.repository.Resent(ctx, transactions)
+	if err != nil {
+		return nil, err
+	}
+	batch := make([]domain.Transaction, 0, len(transactions))
+	for i, tx := range transactions {
+		if !resent[i] {
+			batch = append(batch, tx)
+		}
+	}
+	return batch, nil
+}
+
+// GetFlags returns the transactions of the account flagged while ingested,
+// dated within the calendar days of the range in the account timezone.
+func (s Service) GetFlags(ctx context.Context, accountID domain.AccountID, start, end time.Time) ([]domain.Flag, error) {
+	account, err := s.repository.GetAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.GetFlags(ctx, accountID, domain.DayIn(start, account.Location()), domain.DayIn(end, account.Location()))
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+// memoryRepository keeps the stored transactions of the anomaly checks,
+// telling them apart by reference.
+type memoryRepository struct {
+	repository
+	stored []domain.Transaction
+}
+
+func (r *memoryRepository) AmountStats(context.Context, domain.AccountID) (domain.AmountStats, error) {
+	return domain.AmountStats{Count: len(r.stored)}, nil
+}
+
+func (r *memoryRepository) TransactionsBetween(_ context.Context, _ domain.AccountID, start, end time.Time) ([]domain.Transaction, error) {
+	var between []domain.Transaction
+	for _, tx := range r.stored {
+		if !tx.Date.Before(start) && !tx.Date.After(end) {
+			between = append(between, tx)
+		}
+	}
+	return between, nil
+}
+
+func (r *memoryRepository) Resent(_ context.Context, transactions []domain.Transaction) ([]bool, error) {
+	seen := make(map[string]bool)
+	for _, tx := range r.stored {
+		seen[tx.Reference] = true
+	}
+	resent := make([]bool, len(transactions))
+	for i, tx := range transactions {
+		resent[i] = seen[tx.Reference]
+		seen[tx.Reference] = true
+	}
+	return resent, nil
+}
+
+func TestDetectAnomaliesResent(t *testing.T) {
+	start := time.Date(2023, 10, 5, 12, 0, 0, 0, time.UTC)
+	var stored []domain.Transaction
+	for i := 0; i < 5; i++ {
+		stored = append(stored, domain.Transaction{
+			AccountID: "acc", Amount: -10, Date: start.Add(time.Duration(i) * time.Minute), Reference: fmt.Sprint("stored-", i),
+		})
+	}
+	s := Service{
+		repository:   &memoryRepository{stored: stored},
+		anomalyRules: []AnomalyRule{BurstRule{Window: 10 * time.Minute, Max: 5}, DuplicateRule{}},
+	}
+
+	// the file is sent again, with one line repeated and a new transaction
+	// with the amount and time of a stored one
+	incoming := append(append([]domain.Transaction{}, stored...), stored[4],
+		domain.Transaction{AccountID: "acc", Amount: -10, Date: stored[4].Date, Reference: "new"})
+	flags, err := s.detectAnomalies(context.Background(), incoming)
+	if err != nil {
+		t.Fatalf("detectAnomalies: %v", err)
+	}
+
+	var got []string
+	for _, flag := range flags {
+		got = append(got, flag.Policy+" "+flag.Transaction.Reference)
+	}
+	want := []string{"burst new", "duplicate new"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("flags = %v, want %v", got, want)
+	}
+}
+
+func TestLargeAmountRule(t *testing.T) {
+	rule := LargeAmountRule{Factor: 5, Deviations: 3, MinHistory: 10}
+	tests := []struct {
+		name   string
+		stats  domain.AmountStats
+		amount float64
+		flag   bool
+	}{
+		{name: "short history", stats: domain.AmountStats{Count: 9, Mean: 10}, amount: 1000},
+		{name: "steady account", stats: domain.AmountStats{Count: 10, Mean: 10, StdDev: 1}, amount: -51, flag: true},
+		{name: "under the factor", stats: domain.AmountStats{Count: 10, Mean: 10, StdDev: 1}, amount: 49},
+		{name: "within the deviations", stats: domain.AmountStats{Count: 10, Mean: 10, StdDev: 20}, amount: 69},
+		{name: "beyond the deviations", stats: domain.AmountStats{Count: 10, Mean: 10, StdDev: 20}, amount: 71, flag: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := rule.Check(AnomalyInput{Stats: tt.stats, Transactions: []domain.Transaction{{Amount: tt.amount}}})
+			if flagged := len(flags) > 0; flagged != tt.flag {
+				t.Fatalf("flagged %v, want %v", flagged, tt.flag)
+			}
+		})
+	}
+}
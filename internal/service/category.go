@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+func (s Service) CreateCategoryRule(ctx context.Context, rule domain.CategoryRule) (domain.CategoryRule, error) {
+	return s.repository.CreateCategoryRule(ctx, rule)
+}
+
+func (s Service) UpdateCategoryRule(ctx context.Context, rule domain.CategoryRule) (domain.CategoryRule, error) {
+	return s.repository.UpdateCategoryRule(ctx, rule)
+}
+
+func (s Service) DeleteCategoryRule(ctx context.Context, id string) error {
+	return s.repository.DeleteCategoryRule(ctx, id)
+}
+
+func (s Service) GetCategoryRule(ctx context.Context, id string) (domain.CategoryRule, error) {
+	return s.repository.GetCategoryRule(ctx, id)
+}
+
+func (s Service) ListCategoryRules(ctx context.Context, accountID domain.AccountID) ([]domain.CategoryRule, error) {
+	return s.repository.ListCategoryRules(ctx, accountID)
+}
@@ -2,14 +2,22 @@ package service
 
 import (
 	"context"
+	"errors"
+
 	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
 )
 
 type (
 	projection interface {
 		CreateAccount(ctx context.Context, account domain.Account) error
+		UpdatePreferences(ctx context.Context, preferences domain.Preferences) error
+		UpdatePolicy(ctx context.Context, policy domain.Policy) error
 		RegisterTransaction(ctx context.Context, tx domain.Transaction) error
-		RegisterSummary(ctx context.Context, tx domain.Transaction) error
+		RegisterSummary(ctx context.Context, eventID string, tx domain.Transaction) error
+		ReverseSummary(ctx context.Context, tx domain.Transaction) error
+		TransactionCategory(ctx context.Context, eventID string) (string, error)
+		CategoryRules(ctx context.Context, accountID domain.AccountID) ([]domain.CategoryRule, error)
 	}
 
 	EventService struct {
@@ -25,10 +33,54 @@ func (e EventService) CreateAccount(ctx context.Context, account domain.Account)
 	return e.repository.CreateAccount(ctx, account)
 }
 
+func (e EventService) UpdatePreferences(ctx context.Context, preferences domain.Preferences) error {
+	return e.repository.UpdatePreferences(ctx, preferences)
+}
+
+func (e EventService) UpdatePolicy(ctx context.Context, policy domain.Policy) error {
+	return e.repository.UpdatePolicy(ctx, policy)
+}
+
 func (e EventService) RegisterTransaction(ctx context.Context, tx domain.Transaction) error {
 	return e.repository.RegisterTransaction(ctx, tx)
 }
 
-func (e EventService) RegisterSummary(ctx context.Context, tx domain.Transaction) error {
-	return e.repository.RegisterSummary(ctx, tx)
+// RegisterSummary categorizes the transaction of the event with the rules of
+// its account before adding it to the summaries.
+func (e EventService) RegisterSummary(ctx context.Context, eventID string, tx domain.Transaction) error {
+	rules, err := e.repository.CategoryRules(ctx, tx.AccountID)
+	if err != nil {
+		return err
+	}
+	tx.Category = domain.Categorize(tx, rules)
+	return e.repository.RegisterSummary(ctx, eventID, tx)
+}
+
+// ReverseTransaction takes the amount of the reversed transaction back from
+// the balance of its account.
+func (e EventService) ReverseTransaction(ctx context.Context, reversal domain.Reversal) error {
+	tx := reversal.Transaction
+	tx.Amount = -tx.Amount
+	return e.repository.RegisterTransaction(ctx, tx)
+}
+
+// ReverseSummary takes the reversed transaction out of the summaries it was
+// added to, under the category it was registered with. Transactions
+// registered before categories were kept get theirs from the current rules.
+func (e EventService) ReverseSummary(ctx context.Context, reversal domain.Reversal) error {
+	tx := reversal.Transaction
+	category, err := e.repository.TransactionCategory(ctx, reversal.EventID)
+	switch {
+	case err == nil:
+		tx.Category = category
+	case errors.Is(err, pkgError.ErrNotFound):
+		rules, err := e.repository.CategoryRules(ctx, tx.AccountID)
+		if err != nil {
+			return err
+		}
+		tx.Category = domain.Categorize(tx, rules)
+	default:
+		return err
+	}
+	return e.repository.ReverseSummary(ctx, tx)
 }
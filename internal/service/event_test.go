@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+// memoryProjection keeps the categories registered per event and the
+// transactions reversed.
+type memoryProjection struct {
+	projection
+	rules      []domain.CategoryRule
+	categories map[string]string
+	reversed   []domain.Transaction
+}
+
+func (p *memoryProjection) CategoryRules(context.Context, domain.AccountID) ([]domain.CategoryRule, error) {
+	return p.rules, nil
+}
+
+func (p *memoryProjection) RegisterSummary(_ context.Context, eventID string, tx domain.Transaction) error {
+	p.categories[eventID] = tx.Category
+	return nil
+}
+
+func (p *memoryProjection) TransactionCategory(_ context.Context, eventID string) (string, error) {
+	category, ok := p.categories[eventID]
+	if !ok {
+		return "", pkgError.NotFound("transaction category")
+	}
+	return category, nil
+}
+
+func (p *memoryProjection) ReverseSummary(_ context.Context, tx domain.Transaction) error {
+	p.reversed = append(p.reversed, tx)
+	return nil
+}
+
+func TestReverseSummaryCategory(t *testing.T) {
+	rule := func(category string) []domain.CategoryRule {
+		return []domain.CategoryRule{{Field: domain.RuleFieldMerchant, Match: domain.RuleMatchContains, Pattern: "cafe", Category: category}}
+	}
+	tx := domain.Transaction{AccountID: "acc", Amount: -4, Date: time.Now(), Merchant: "Cafe Tortoni"}
+
+	p := &memoryProjection{rules: rule("coffee"), categories: map[string]string{}}
+	events := NewEventService(p)
+	if err := events.RegisterSummary(context.Background(), "registered", tx); err != nil {
+		t.Fatalf("RegisterSummary: %v", err)
+	}
+
+	// the rule changes between the registration and the reversals
+	p.rules = rule("restaurants")
+	for _, eventID := range []string{"registered", "before categories were kept"} {
+		if err := events.ReverseSummary(context.Background(), domain.Reversal{EventID: eventID, Transaction: tx}); err != nil {
+			t.Fatalf("ReverseSummary %s: %v", eventID, err)
+		}
+	}
+
+	if got := p.reversed[0].Category; got != "coffee" {
+		t.Errorf("reversed under %q, want the registered category %q", got, "coffee")
+	}
+	if got := p.reversed[1].Category; got != "restaurants" {
+		t.Errorf("reversed an unknown event under %q, want the current category %q", got, "restaurants")
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+type (
+	// Notifier delivers a queued notification through one channel.
+	Notifier interface {
+		Channel() domain.Channel
+		Notify(ctx context.Context, delivery domain.Delivery) error
+	}
+)
+
+// DispatchNotifications sends up to batch due deliveries, each one through
+// the notifier registered for its channel, and records the outcome.
+func (s Service) DispatchNotifications(ctx context.Context, batch int) error {
+	deliveries, err := s.repository.ClaimNotifications(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	var errs error
+	for _, delivery := range deliveries {
+		var errSend error
+		if notifier, ok := s.notifiers[delivery.Channel]; ok {
+			errSend = notifier.Notify(ctx, delivery)
+		} else {
+			errSend = fmt.Errorf("no notifier for channel %q", delivery.Channel)
+		}
+		if err = s.repository.CompleteNotification(ctx, delivery, errSend); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
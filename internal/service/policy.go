@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+func (s Service) UpdatePolicy(ctx context.Context, policy domain.Policy) error {
+	return s.repository.UpdatePolicy(ctx, policy)
+}
+
+type policyState struct {
+	account domain.Account
+	balance float64
+	known   bool
+}
+
+// checkPolicies runs the transactions, in date order, against the overdraft
+// policy of their accounts, starting from the balance rebuilt from the event
+// store. It returns the transactions to store and the report of the ones
+// rejected or flagged. Transactions of unknown accounts are not checked.
+// Transactions already stored, or repeated in the batch, are left out: the
+// rebuilt balance already counts them and storing them again does nothing.
+func (s Service) checkPolicies(ctx context.Context, transactions []domain.Transaction) ([]domain.Transaction, domain.ProcessingReport, error) {
+	report := domain.ProcessingReport{
+		Received: len(transactions),
+		Rejected: make([]domain.Violation, 0),
+		Flagged:  make([]domain.Violation, 0),
+	}
+	sorted, err := s.unsent(ctx, transactions)
+	if err != nil {
+		return nil, report, err
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Date.Before(sorted[j].Date) })
+
+	states := make(map[domain.AccountID]*policyState)
+	accepted := make([]domain.Transaction, 0, len(sorted))
+	for _, tx := range sorted {
+		state, ok := states[tx.AccountID]
+		if !ok {
+			if state, err = s.policyState(ctx, tx.AccountID); err != nil {
+				return nil, report, err
+			}
+			states[tx.AccountID] = state
+		}
+
+		if state.known && state.account.Overdrawn(state.balance, tx.Amount) {
+			violation := domain.Violation{
+				Transaction: tx,
+				Policy:      string(state.account.OverdraftPolicy),
+				Reason:      "insufficient funds",
+				Balance:     state.balance,
+			}
+			if state.account.OverdraftPolicy == domain.OverdraftAllow {
+				violation.Reason = "overdraft limit exceeded"
+			}
+			if state.account.OverdraftPolicy != domain.OverdraftFlag {
+				report.Rejected = append(report.Rejected, violation)
+				continue
+			}
+			report.Flagged = append(report.Flagged, violation)
+		}
+		state.balance += tx.Amount
+		accepted = append(accepted, tx)
+	}
+	report.Accepted = len(accepted)
+	return accepted, report, nil
+}
+
+func (s Service) policyState(ctx context.Context, accountID domain.AccountID) (*policyState, error) {
+	account, err := s.repository.GetAccount(ctx, accountID)
+	if errors.Is(err, pkgError.ErrNotFound) {
+		return &policyState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	balance, err := s.repository.RehydrateBalance(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return &policyState{account: account, balance: balance, known: true}, nil
+}
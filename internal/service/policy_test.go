@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+// policyRepository adds the accounts and the balances rebuilt from the
+// stored transactions to memoryRepository.
+type policyRepository struct {
+	memoryRepository
+	accounts map[domain.AccountID]domain.Account
+}
+
+func (r *policyRepository) GetAccount(_ context.Context, accountID domain.AccountID) (domain.Account, error) {
+	account, ok := r.accounts[accountID]
+	if !ok {
+		return domain.Account{}, pkgError.NotFound("account")
+	}
+	return account, nil
+}
+
+func (r *policyRepository) RehydrateBalance(_ context.Context, accountID domain.AccountID) (float64, error) {
+	var balance float64
+	for _, tx := range r.stored {
+		if tx.AccountID == accountID {
+			balance += tx.Amount
+		}
+	}
+	return balance, nil
+}
+
+func TestCheckPolicies(t *testing.T) {
+	day := time.Date(2023, 10, 5, 12, 0, 0, 0, time.UTC)
+	tx := func(accountID domain.AccountID, amount float64, hour int, reference string) domain.Transaction {
+		return domain.Transaction{AccountID: accountID, Amount: amount, Date: day.Add(time.Duration(hour) * time.Hour), Reference: reference}
+	}
+	account := func(policy domain.OverdraftPolicy, limit float64) domain.Account {
+		return domain.Account{ID: "acc", OverdraftPolicy: policy, OverdraftLimit: limit}
+	}
+
+	tests := []struct {
+		name     string
+		account  domain.Account
+		stored   []domain.Transaction
+		incoming []domain.Transaction
+		accepted []string
+		rejected []string
+		flagged  []string
+	}{
+		{
+			name:     "reject",
+			account:  account(domain.OverdraftReject, 0),
+			stored:   []domain.Transaction{tx("acc", 100, 0, "salary")},
+			incoming: []domain.Transaction{tx("acc", -120, 2, "rent"), tx("acc", -60, 3, "food"), tx("acc", -50, 4, "bills")},
+			accepted: []string{"food"},
+			rejected: []string{"rent", "bills"},
+		},
+		{
+			name:     "flag",
+			account:  account(domain.OverdraftFlag, 0),
+			stored:   []domain.Transaction{tx("acc", 100, 0, "salary")},
+			incoming: []domain.Transaction{tx("acc", -120, 2, "rent"), tx("acc", 50, 3, "refund")},
+			accepted: []string{"rent", "refund"},
+			flagged:  []string{"rent"},
+		},
+		{
+			name:     "allow with limit",
+			account:  account(domain.OverdraftAllow, 50),
+			stored:   []domain.Transaction{tx("acc", 100, 0, "salary")},
+			incoming: []domain.Transaction{tx("acc", -140, 2, "rent"), tx("acc", -20, 3, "food"), tx("acc", -10, 4, "bus")},
+			accepted: []string{"rent", "bus"},
+			rejected: []string{"food"},
+		},
+		{
+			name:     "in date order",
+			account:  account(domain.OverdraftReject, 0),
+			incoming: []domain.Transaction{tx("acc", -80, 3, "rent"), tx("acc", 100, 1, "salary")},
+			accepted: []string{"salary", "rent"},
+		},
+		{
+			name:     "unknown account",
+			account:  account(domain.OverdraftReject, 0),
+			incoming: []domain.Transaction{tx("other", -500, 1, "unknown")},
+			accepted: []string{"unknown"},
+		},
+		{
+			name:     "resent debit",
+			account:  account(domain.OverdraftReject, 0),
+			stored:   []domain.Transaction{tx("acc", 100, 0, "salary"), tx("acc", -80, 1, "rent")},
+			incoming: []domain.Transaction{tx("acc", -80, 1, "rent"), tx("acc", -15, 2, "food")},
+			accepted: []string{"food"},
+		},
+		{
+			name:     "debit repeated in the batch",
+			account:  account(domain.OverdraftReject, 0),
+			stored:   []domain.Transaction{tx("acc", 100, 0, "salary")},
+			incoming: []domain.Transaction{tx("acc", -80, 1, "rent"), tx("acc", -80, 1, "rent")},
+			accepted: []string{"rent"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Service{repository: &policyRepository{
+				memoryRepository: memoryRepository{stored: tt.stored},
+				accounts:         map[domain.AccountID]domain.Account{"acc": tt.account},
+			}}
+			accepted, report, err := s.checkPolicies(context.Background(), tt.incoming)
+			if err != nil {
+				t.Fatalf("checkPolicies: %v", err)
+			}
+
+			if report.Received != len(tt.incoming) || report.Accepted != len(tt.accepted) {
+				t.Errorf("received %d and accepted %d, want %d and %d", report.Received, report.Accepted, len(tt.incoming), len(tt.accepted))
+			}
+			assertReferences(t, "accepted", accepted, tt.accepted)
+			assertReferences(t, "rejected", transactionsOf(report.Rejected), tt.rejected)
+			assertReferences(t, "flagged", transactionsOf(report.Flagged), tt.flagged)
+		})
+	}
+}
+
+func transactionsOf(violations []domain.Violation) []domain.Transaction {
+	var transactions []domain.Transaction
+	for _, violation := range violations {
+		transactions = append(transactions, violation.Transaction)
+	}
+	return transactions
+}
+
+func assertReferences(t *testing.T, name string, transactions []domain.Transaction, want []string) {
+	t.Helper()
+	var got []string
+	for _, tx := range transactions {
+		got = append(got, tx.Reference)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s %v, want %v", name, got, want)
+	}
+}
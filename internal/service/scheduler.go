@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+const summarySchedulerLock = "summary_scheduler"
+
+// SendScheduledSummaries queues the summary of the month before the UTC
+// calendar day of now for every active account; the summary covers that
+// month in the account timezone. Only the replica holding the scheduler lock
+// sends, and each (account, period) pair is reserved first so a customer
+// never receives the same summary twice.
+func (s Service) SendScheduledSummaries(ctx context.Context, now time.Time) error {
+	release, acquired, err := s.repository.AcquireLock(ctx, summarySchedulerLock)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		log.Printf("summary scheduler: lock held by another replica")
+		return nil
+	}
+	defer release()
+
+	accounts, err := s.repository.ListActiveAccounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the day the schedule fired on in UTC, not in the account timezone:
+	// accounts behind UTC are still in the last day of the month
+	year, month, _ := now.UTC().Date()
+	end := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, -1, 0)
+	period := domain.GranularityMonth.Key(start)
+
+	var errs error
+	for _, account := range accounts {
+		reserved, err := s.repository.ReserveSummaryDelivery(ctx, account.ID, period)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		if !reserved {
+			continue
+		}
+
+		if _, err = s.SendSummary(ctx, account.ID, domain.GranularityMonth, start, end); err != nil {
+			if errRelease := s.repository.ReleaseSummaryDelivery(ctx, account.ID, period); errRelease != nil {
+				errs = errors.Join(errs, errRelease)
+			}
+			if errors.Is(err, pkgError.ErrNotFound) {
+				// no movements in the period, nothing to send
+				continue
+			}
+			errs = errors.Join(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+// schedulerRepository records the periods reserved, and reserves none so
+// nothing is sent.
+type schedulerRepository struct {
+	repository
+	accounts []domain.Account
+	reserved map[domain.AccountID]string
+}
+
+func (r *schedulerRepository) AcquireLock(context.Context, string) (func(), bool, error) {
+	return func() {}, true, nil
+}
+
+func (r *schedulerRepository) ListActiveAccounts(context.Context) ([]domain.Account, error) {
+	return r.accounts, nil
+}
+
+func (r *schedulerRepository) ReserveSummaryDelivery(_ context.Context, accountID domain.AccountID, period string) (bool, error) {
+	r.reserved[accountID] = period
+	return false, nil
+}
+
+func TestSendScheduledSummariesPeriod(t *testing.T) {
+	r := &schedulerRepository{
+		accounts: []domain.Account{
+			{ID: "honolulu", Timezone: "Pacific/Honolulu"},
+			{ID: "utc"},
+			{ID: "auckland", Timezone: "Pacific/Auckland"},
+		},
+		reserved: make(map[domain.AccountID]string),
+	}
+	s := Service{repository: r}
+
+	// still the evening of October 31st in Honolulu
+	if err := s.SendScheduledSummaries(context.Background(), time.Date(2026, 11, 1, 8, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("SendScheduledSummaries: %v", err)
+	}
+	for _, account := range r.accounts {
+		if period := r.reserved[account.ID]; period != "2026-10" {
+			t.Errorf("account %s got the summary of %q, want 2026-10", account.ID, period)
+		}
+	}
+}
@@ -6,30 +6,74 @@ import (
 	"time"
 
 	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
 )
 
+const processFilesLock = "process_files"
+
 type (
 	repository interface {
 		CreateAccount(ctx context.Context, account domain.Account) (domain.AccountID, error)
 		SaveTransactions(ctx context.Context, transactions []domain.Transaction) error
+		ReverseTransaction(ctx context.Context, eventID, reason string) (string, error)
+		SaveTransfer(ctx context.Context, transfer domain.Transfer) (string, error)
+		SaveRejections(ctx context.Context, violations []domain.Violation) error
+		RehydrateBalance(ctx context.Context, accountID domain.AccountID) (float64, error)
+		AmountStats(ctx context.Context, accountID domain.AccountID) (domain.AmountStats, error)
+		TransactionsBetween(ctx context.Context, accountID domain.AccountID, start, end time.Time) ([]domain.Transaction, error)
+		Resent(ctx context.Context, transactions []domain.Transaction) ([]bool, error)
+		SaveFlags(ctx context.Context, violations []domain.Violation) error
+		GetFlags(ctx context.Context, accountID domain.AccountID, start, end time.Time) ([]domain.Flag, error)
 
 		SaveTransactionsInDirectory(ctx context.Context, transactions []domain.Transaction) error
 		GetTransactionFromDirectory(ctx context.Context) ([]domain.Transaction, error)
 		DeleteTransactionsInDirectory(ctx context.Context) error
 
-		SendEmail(ctx context.Context, accountID domain.AccountID, start, end time.Time) error
+		SendSummary(ctx context.Context, account domain.Account, report domain.SummaryReport) ([]string, error)
+		GetNotification(ctx context.Context, id string) (domain.Notification, error)
+
+		GetAccount(ctx context.Context, accountID domain.AccountID) (domain.Account, error)
+		ListAccounts(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error)
+		GetBalance(ctx context.Context, accountID domain.AccountID) (domain.Balance, error)
+		GetSummaries(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) ([]domain.Summary, error)
+		GetTransactions(ctx context.Context, accountID domain.AccountID, filter domain.TransactionFilter) (domain.TransactionPage, error)
+		ExportSummary(ctx context.Context, account domain.Account, report domain.SummaryReport, format domain.ReportFormat) (domain.Report, error)
+		GetCategorySpending(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) ([]domain.CategorySpending, error)
+
+		CreateCategoryRule(ctx context.Context, rule domain.CategoryRule) (domain.CategoryRule, error)
+		UpdateCategoryRule(ctx context.Context, rule domain.CategoryRule) (domain.CategoryRule, error)
+		DeleteCategoryRule(ctx context.Context, id string) error
+		GetCategoryRule(ctx context.Context, id string) (domain.CategoryRule, error)
+		ListCategoryRules(ctx context.Context, accountID domain.AccountID) ([]domain.CategoryRule, error)
+
+		ListActiveAccounts(ctx context.Context) ([]domain.Account, error)
+		ReserveSummaryDelivery(ctx context.Context, accountID domain.AccountID, period string) (bool, error)
+		ReleaseSummaryDelivery(ctx context.Context, accountID domain.AccountID, period string) error
+		AcquireLock(ctx context.Context, name string) (func(), bool, error)
+
+		ClaimNotifications(ctx context.Context, batch int) ([]domain.Delivery, error)
+		CompleteNotification(ctx context.Context, delivery domain.Delivery, errSend error) error
+
+		UpdatePreferences(ctx context.Context, preferences domain.Preferences) error
+		UpdatePolicy(ctx context.Context, policy domain.Policy) error
 	}
 
 	Service struct {
-		repository repository
+		repository   repository
+		notifiers    map[domain.Channel]Notifier
+		anomalyRules []AnomalyRule
 	}
 )
 
-func NewService(repository repository) (*Service, error) {
+func NewService(repository repository, notifiers ...Notifier) (*Service, error) {
 	if repository == nil {
 		return nil, errors.New("repository should not be nil")
 	}
-	return &Service{repository: repository}, nil
+	registry := make(map[domain.Channel]Notifier, len(notifiers))
+	for _, notifier := range notifiers {
+		registry[notifier.Channel()] = notifier
+	}
+	return &Service{repository: repository, notifiers: registry, anomalyRules: DefaultAnomalyRules()}, nil
 }
 
 func (s Service) CreateAccount(ctx context.Context, account domain.Account) (domain.AccountID, error) {
@@ -40,18 +84,87 @@ func (s Service) SaveTransactions(ctx context.Context, transactions []domain.Tra
 	return s.repository.SaveTransactionsInDirectory(ctx, transactions)
 }
 
-func (s Service) ProcessFiles(ctx context.Context) error {
+// ProcessFiles stores the staged transactions that pass the overdraft policy
+// of their account and records the rejected ones, then deletes the staged
+// files. The stored transactions that are overdrawn or caught by an anomaly
+// rule are flagged. One replica processes the files at a time.
+func (s Service) ProcessFiles(ctx context.Context) (domain.ProcessingReport, error) {
+	release, acquired, err := s.repository.AcquireLock(ctx, processFilesLock)
+	if err != nil {
+		return domain.ProcessingReport{}, err
+	}
+	if !acquired {
+		return domain.ProcessingReport{}, pkgError.ErrProcessingInProgress
+	}
+	defer release()
+
 	tx, err := s.repository.GetTransactionFromDirectory(ctx)
 	if err != nil {
-		return err
+		return domain.ProcessingReport{}, err
+	}
+
+	accepted, report, err := s.checkPolicies(ctx, tx)
+	if err != nil {
+		return domain.ProcessingReport{}, err
+	}
+	anomalies, err := s.detectAnomalies(ctx, accepted)
+	if err != nil {
+		return domain.ProcessingReport{}, err
+	}
+	report.Flagged = append(report.Flagged, anomalies...)
+
+	if err = s.repository.SaveTransactions(ctx, accepted); err != nil {
+		return domain.ProcessingReport{}, err
 	}
+	if err = s.repository.SaveRejections(ctx, report.Rejected); err != nil {
+		return domain.ProcessingReport{}, err
+	}
+	if err = s.repository.SaveFlags(ctx, report.Flagged); err != nil {
+		return domain.ProcessingReport{}, err
+	}
+	return report, s.repository.DeleteTransactionsInDirectory(ctx)
+}
+
+func (s Service) ReverseTransaction(ctx context.Context, eventID, reason string) (string, error) {
+	return s.repository.ReverseTransaction(ctx, eventID, reason)
+}
+
+func (s Service) UpdatePreferences(ctx context.Context, preferences domain.Preferences) error {
+	return s.repository.UpdatePreferences(ctx, preferences)
+}
+
+func (s Service) GetAccount(ctx context.Context, accountID domain.AccountID) (domain.Account, error) {
+	return s.repository.GetAccount(ctx, accountID)
+}
 
-	if err = s.repository.SaveTransactions(ctx, tx); err != nil {
-		return err
+func (s Service) ListAccounts(ctx context.Context, filter domain.AccountFilter) (domain.AccountPage, error) {
+	return s.repository.ListAccounts(ctx, filter)
+}
+
+func (s Service) GetBalance(ctx context.Context, accountID domain.AccountID) (domain.Balance, error) {
+	return s.repository.GetBalance(ctx, accountID)
+}
+
+func (s Service) GetSummaries(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) ([]domain.Summary, error) {
+	return s.repository.GetSummaries(ctx, accountID, granularity, start, end)
+}
+
+// GetTransactions reads the calendar days of the filter in the account
+// timezone, the same days its summaries are bucketed in.
+func (s Service) GetTransactions(ctx context.Context, accountID domain.AccountID, filter domain.TransactionFilter) (domain.TransactionPage, error) {
+	account, err := s.repository.GetAccount(ctx, accountID)
+	if err != nil {
+		return domain.TransactionPage{}, err
+	}
+	if !filter.Start.IsZero() {
+		filter.Start = domain.DayIn(filter.Start, account.Location())
+	}
+	if !filter.End.IsZero() {
+		filter.End = domain.DayIn(filter.End, account.Location())
 	}
-	return s.repository.DeleteTransactionsInDirectory(ctx)
+	return s.repository.GetTransactions(ctx, accountID, filter)
 }
 
-func (s Service) SendEmail(ctx context.Context, accountID domain.AccountID, start, end time.Time) error {
-	return s.repository.SendEmail(ctx, accountID, start, end)
+func (s Service) GetNotification(ctx context.Context, id string) (domain.Notification, error) {
+	return s.repository.GetNotification(ctx, id)
 }
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+	pkgError "github.com/castiglionimax/process-csv/pkg/error"
+)
+
+// CalculateSummary builds the summary report of periods, which must be sorted
+// and cover the whole range. balance is the current balance of the account.
+// The range opens with the opening balance of its first period and closes
+// with the closing balance of the last one, periods without movements have
+// no row and do not change the balance.
+func CalculateSummary(accountID domain.AccountID, periods []domain.Summary, balance float64) domain.SummaryReport {
+	report := domain.SummaryReport{
+		AccountID: accountID,
+		Balance:   balance,
+		Periods:   make([]domain.PeriodStatistics, 0, len(periods)),
+	}
+
+	var totals domain.Statistics
+	for _, period := range periods {
+		statistics := periodStatistics(period)
+		report.Periods = append(report.Periods, domain.PeriodStatistics{
+			Period:         period.Period,
+			OpeningBalance: period.OpeningBalance,
+			ClosingBalance: period.ClosingBalance,
+			Statistics:     statistics,
+		})
+
+		totals.Credit += statistics.Credit
+		totals.Debit += statistics.Debit
+		totals.TransferIn += statistics.TransferIn
+		totals.TransferInQty += statistics.TransferInQty
+		totals.TransferOut += statistics.TransferOut
+		totals.TransferOutQty += statistics.TransferOutQty
+		if statistics.CreditQty > 0 {
+			totals.MinCredit = minSize(totals.MinCredit, totals.CreditQty, statistics.MinCredit)
+			totals.MaxCredit = max(totals.MaxCredit, statistics.MaxCredit)
+			totals.CreditQty += statistics.CreditQty
+		}
+		if statistics.DebitQty > 0 {
+			totals.MinDebit = minSize(totals.MinDebit, totals.DebitQty, statistics.MinDebit)
+			totals.MaxDebit = max(totals.MaxDebit, statistics.MaxDebit)
+			totals.DebitQty += statistics.DebitQty
+		}
+	}
+	totals.Movements = totals.CreditQty + totals.DebitQty + totals.TransferInQty + totals.TransferOutQty
+	totals.AverageCredit = average(totals.Credit, totals.CreditQty)
+	totals.AverageDebit = average(totals.Debit, totals.DebitQty)
+	totals.NetFlow = totals.Credit + totals.Debit + totals.TransferIn + totals.TransferOut
+
+	report.Totals = totals
+	if len(periods) > 0 {
+		report.OpeningBalance = periods[0].OpeningBalance
+		report.ClosingBalance = periods[len(periods)-1].ClosingBalance
+	}
+	return report
+}
+
+func periodStatistics(period domain.Summary) domain.Statistics {
+	return domain.Statistics{
+		Movements:      period.CreditQty + period.DebitQty + period.TransferInQty + period.TransferOutQty,
+		Credit:         period.Credit,
+		CreditQty:      period.CreditQty,
+		Debit:          period.Debit,
+		DebitQty:       period.DebitQty,
+		TransferIn:     period.TransferIn,
+		TransferInQty:  period.TransferInQty,
+		TransferOut:    period.TransferOut,
+		TransferOutQty: period.TransferOutQty,
+		AverageCredit:  average(period.Credit, period.CreditQty),
+		AverageDebit:   average(period.Debit, period.DebitQty),
+		MinCredit:      period.MinCredit,
+		MaxCredit:      period.MaxCredit,
+		MinDebit:       period.MinDebit,
+		MaxDebit:       period.MaxDebit,
+		NetFlow:        period.Credit + period.Debit + period.TransferIn + period.TransferOut,
+	}
+}
+
+func average(amount float64, qty int) float64 {
+	if qty == 0 {
+		return 0
+	}
+	return amount / float64(qty)
+}
+
+// minSize keeps the smallest size seen, current is meaningless while no
+// movement was counted yet.
+func minSize(current float64, counted int, size float64) float64 {
+	if counted == 0 {
+		return size
+	}
+	return min(current, size)
+}
+
+// summaryReport loads the summaries of the range and computes its report,
+// with the spending by category and the flagged movements of the same
+// periods.
+func (s Service) summaryReport(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) (domain.Account, domain.SummaryReport, error) {
+	account, err := s.repository.GetAccount(ctx, accountID)
+	if err != nil {
+		return domain.Account{}, domain.SummaryReport{}, err
+	}
+	periods, err := s.repository.GetSummaries(ctx, accountID, granularity, start, end)
+	if err != nil {
+		return domain.Account{}, domain.SummaryReport{}, err
+	}
+	if len(periods) == 0 {
+		return domain.Account{}, domain.SummaryReport{}, pkgError.NotFound("summary")
+	}
+	balance, err := s.repository.GetBalance(ctx, accountID)
+	if err != nil {
+		return domain.Account{}, domain.SummaryReport{}, err
+	}
+	report := CalculateSummary(accountID, periods, balance.Amount)
+	report.Granularity = granularity
+	if report.Categories, err = s.repository.GetCategorySpending(ctx, accountID, granularity, start, end); err != nil {
+		return domain.Account{}, domain.SummaryReport{}, err
+	}
+	location := account.Location()
+	report.Flags, err = s.repository.GetFlags(ctx, accountID,
+		domain.DayIn(granularity.Start(start), location), domain.DayIn(end, location))
+	if err != nil {
+		return domain.Account{}, domain.SummaryReport{}, err
+	}
+	return account, report, nil
+}
+
+func (s Service) SendSummary(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time) ([]string, error) {
+	account, report, err := s.summaryReport(ctx, accountID, granularity, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.SendSummary(ctx, account, report)
+}
+
+func (s Service) ExportSummary(ctx context.Context, accountID domain.AccountID, granularity domain.Granularity, start, end time.Time, format domain.ReportFormat) (domain.Report, error) {
+	account, report, err := s.summaryReport(ctx, accountID, granularity, start, end)
+	if err != nil {
+		return domain.Report{}, err
+	}
+	return s.repository.ExportSummary(ctx, account, report, format)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+func TestCalculateSummary(t *testing.T) {
+	september := domain.Summary{
+		Period: "2023-09", Credit: 1200, CreditQty: 2, Debit: -450, DebitQty: 3,
+		MinCredit: 200, MaxCredit: 1000, MinDebit: 50, MaxDebit: 250,
+		OpeningBalance: 100, ClosingBalance: 850,
+	}
+	october := domain.Summary{
+		Period: "2023-10", Credit: 300, CreditQty: 1, Debit: -30, DebitQty: 1,
+		TransferIn: 500, TransferInQty: 1, TransferOut: -120, TransferOutQty: 2,
+		MinCredit: 300, MaxCredit: 300, MinDebit: 30, MaxDebit: 30,
+		OpeningBalance: 850, ClosingBalance: 1500,
+	}
+	// the row carrying the balance of a period without movements
+	empty := domain.Summary{Period: "2023-11", OpeningBalance: 1500, ClosingBalance: 1500}
+	// its only debit was reversed, the sizes seen are kept but count no more
+	reversed := domain.Summary{
+		Period: "2023-12", Credit: 80, CreditQty: 1, MinCredit: 80, MaxCredit: 80, MinDebit: 5, MaxDebit: 900,
+		OpeningBalance: 1500, ClosingBalance: 1580,
+	}
+
+	tests := []struct {
+		name    string
+		periods []domain.Summary
+		totals  domain.Statistics
+		opening float64
+		closing float64
+	}{
+		{name: "no periods", periods: nil},
+		{name: "one period", periods: []domain.Summary{september}, opening: 100, closing: 850, totals: domain.Statistics{
+			Movements: 5, Credit: 1200, CreditQty: 2, Debit: -450, DebitQty: 3,
+			AverageCredit: 600, AverageDebit: -150, MinCredit: 200, MaxCredit: 1000, MinDebit: 50, MaxDebit: 250, NetFlow: 750,
+		}},
+		{name: "min and max across periods with transfers", periods: []domain.Summary{september, october}, opening: 100, closing: 1500,
+			totals: domain.Statistics{
+				Movements: 10, Credit: 1500, CreditQty: 3, Debit: -480, DebitQty: 4,
+				TransferIn: 500, TransferInQty: 1, TransferOut: -120, TransferOutQty: 2,
+				AverageCredit: 500, AverageDebit: -120, MinCredit: 200, MaxCredit: 1000, MinDebit: 30, MaxDebit: 250, NetFlow: 1400,
+			}},
+		{name: "empty period", periods: []domain.Summary{empty}, opening: 1500, closing: 1500},
+		{name: "empty period first", periods: []domain.Summary{empty, reversed}, opening: 1500, closing: 1580, totals: domain.Statistics{
+			Movements: 1, Credit: 80, CreditQty: 1, AverageCredit: 80, MinCredit: 80, MaxCredit: 80, NetFlow: 80,
+		}},
+		{name: "reversal", periods: []domain.Summary{october, reversed}, opening: 850, closing: 1580, totals: domain.Statistics{
+			Movements: 6, Credit: 380, CreditQty: 2, Debit: -30, DebitQty: 1,
+			TransferIn: 500, TransferInQty: 1, TransferOut: -120, TransferOutQty: 2,
+			AverageCredit: 190, AverageDebit: -30, MinCredit: 80, MaxCredit: 300, MinDebit: 30, MaxDebit: 30, NetFlow: 730,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := CalculateSummary("acc", tt.periods, 1580)
+			if report.AccountID != "acc" || report.Balance != 1580 {
+				t.Errorf("report of %s with balance %v, want acc and 1580", report.AccountID, report.Balance)
+			}
+			if report.OpeningBalance != tt.opening || report.ClosingBalance != tt.closing {
+				t.Errorf("balances %v to %v, want %v to %v", report.OpeningBalance, report.ClosingBalance, tt.opening, tt.closing)
+			}
+			if !reflect.DeepEqual(report.Totals, tt.totals) {
+				t.Errorf("totals\n got %+v\nwant %+v", report.Totals, tt.totals)
+			}
+			if len(report.Periods) != len(tt.periods) {
+				t.Fatalf("%d periods, want %d", len(report.Periods), len(tt.periods))
+			}
+		})
+	}
+}
+
+func TestCalculateSummaryPeriods(t *testing.T) {
+	period := domain.Summary{
+		Period: "2023-10", Credit: 300, CreditQty: 2, Debit: -90, DebitQty: 3, TransferOut: -40, TransferOutQty: 1,
+		MinCredit: 100, MaxCredit: 200, MinDebit: 10, MaxDebit: 50, OpeningBalance: 20, ClosingBalance: 190,
+	}
+	want := domain.PeriodStatistics{
+		Period: "2023-10", OpeningBalance: 20, ClosingBalance: 190,
+		Statistics: domain.Statistics{
+			Movements: 6, Credit: 300, CreditQty: 2, Debit: -90, DebitQty: 3, TransferOut: -40, TransferOutQty: 1,
+			AverageCredit: 150, AverageDebit: -30, MinCredit: 100, MaxCredit: 200, MinDebit: 10, MaxDebit: 50, NetFlow: 170,
+		},
+	}
+
+	report := CalculateSummary("acc", []domain.Summary{period}, 190)
+	if !reflect.DeepEqual(report.Periods, []domain.PeriodStatistics{want}) {
+		t.Fatalf("periods\n got %+v\nwant %+v", report.Periods, want)
+	}
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/castiglionimax/process-csv/internal/domain"
+)
+
+// CreateTransfer moves money between two existing accounts and returns the
+// transfer ID shared by both sides. Transfers without a date take place now.
+func (s Service) CreateTransfer(ctx context.Context, transfer domain.Transfer) (string, error) {
+	for _, accountID := range []domain.AccountID{transfer.From, transfer.To} {
+		if _, err := s.repository.GetAccount(ctx, accountID); err != nil {
+			return "", err
+		}
+	}
+	if transfer.Date.IsZero() {
+		transfer.Date = time.Now().UTC()
+	}
+	return s.repository.SaveTransfer(ctx, transfer)
+}
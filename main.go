@@ -1,6 +1,11 @@
 package main
 
-import "github.com/castiglionimax/process-csv/cmd/api/server"
+import (
+	// account timezones must resolve even where the system has no zoneinfo
+	_ "time/tzdata"
+
+	"github.com/castiglionimax/process-csv/cmd/api/server"
+)
 
 func main() {
 	server.StartApplication()
@@ -1 +1,6 @@
 db.event.createIndex({ "hash": 1 }, { unique: true });
+
+db = db.getSiblingDB("event_store");
+db.accounts.createIndex({ "hash": 1 }, { unique: true });
+db.accounts.createIndex({ "aggregate_id": 1, "time": 1 });
+db.accounts.createIndex({ "aggregate_id": 1, "event_type": 1, "time": 1, "event_id": 1 });
@@ -2,19 +2,106 @@ CREATE TABLE IF NOT EXISTS accounts (
     id VARCHAR(255) PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     email VARCHAR(250) NOT NULL,
+    locale VARCHAR(16) NOT NULL DEFAULT 'en',
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    status VARCHAR(32) NOT NULL DEFAULT 'active',
+    channels VARCHAR(255) NOT NULL DEFAULT 'email',
+    webhook_url VARCHAR(2048) NULL,
+    overdraft_policy VARCHAR(16) NOT NULL DEFAULT 'flag',
+    overdraft_limit DECIMAL(50, 3) NOT NULL DEFAULT 0,
     amount DECIMAL(50, 6) NOT NULL,
-    last_updated DATETIME NOT NULL
+    last_updated DATETIME NOT NULL,
+    UNIQUE INDEX idx_accounts_email (email),
+    INDEX idx_accounts_name (name),
+    INDEX idx_accounts_status (status)
     );
 
 
 CREATE TABLE IF NOT EXISTS summaries (
     account_id VARCHAR(255) NOT NULL,
-    period VARCHAR(255) NOT NULL,
+    granularity VARCHAR(16) NOT NULL,
+    period VARCHAR(16) NOT NULL,
+    period_start DATE NOT NULL,
     credit DECIMAL(50, 3) NOT NULL,
     credit_qty INTEGER NOT NULL,
     debit DECIMAL(50, 3) NOT NULL,
     debit_qty INTEGER NOT NULL,
+    transfer_in DECIMAL(50, 3) NOT NULL DEFAULT 0,
+    transfer_in_qty INTEGER NOT NULL DEFAULT 0,
+    transfer_out DECIMAL(50, 3) NOT NULL DEFAULT 0,
+    transfer_out_qty INTEGER NOT NULL DEFAULT 0,
+    min_credit DECIMAL(50, 3) NULL,
+    max_credit DECIMAL(50, 3) NULL,
+    min_debit DECIMAL(50, 3) NULL,
+    max_debit DECIMAL(50, 3) NULL,
+    opening_balance DECIMAL(50, 3) NOT NULL DEFAULT 0,
+    closing_balance DECIMAL(50, 3) NOT NULL DEFAULT 0,
     last_updated DATETIME NOT NULL,
+    PRIMARY KEY(account_id, granularity, period),
+    INDEX idx_summaries_start (account_id, granularity, period_start),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+    );
+
+CREATE TABLE IF NOT EXISTS category_rules (
+    id VARCHAR(36) PRIMARY KEY,
+    account_id VARCHAR(255) NULL,
+    field VARCHAR(16) NOT NULL,
+    match_type VARCHAR(16) NOT NULL,
+    pattern VARCHAR(512) NOT NULL,
+    category VARCHAR(64) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    INDEX idx_category_rules_account (account_id, priority),
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+    );
+
+CREATE TABLE IF NOT EXISTS category_summaries (
+    account_id VARCHAR(255) NOT NULL,
+    granularity VARCHAR(16) NOT NULL,
+    period VARCHAR(16) NOT NULL,
+    period_start DATE NOT NULL,
+    category VARCHAR(64) NOT NULL,
+    credit DECIMAL(50, 3) NOT NULL,
+    credit_qty INTEGER NOT NULL,
+    debit DECIMAL(50, 3) NOT NULL,
+    debit_qty INTEGER NOT NULL,
+    last_updated DATETIME NOT NULL,
+    PRIMARY KEY(account_id, granularity, period, category),
+    INDEX idx_category_summaries_start (account_id, granularity, period_start),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+    );
+
+CREATE TABLE IF NOT EXISTS transaction_categories (
+    event_id VARCHAR(36) PRIMARY KEY,
+    account_id VARCHAR(255) NOT NULL,
+    category VARCHAR(64) NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+    );
+
+CREATE TABLE IF NOT EXISTS summary_deliveries (
+    account_id VARCHAR(255) NOT NULL,
+    period VARCHAR(32) NOT NULL,
+    sent_at DATETIME NOT NULL,
     PRIMARY KEY(account_id, period),
     FOREIGN KEY (account_id) REFERENCES accounts(id)
-    );
\ No newline at end of file
+    );
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id VARCHAR(36) PRIMARY KEY,
+    account_id VARCHAR(255) NOT NULL,
+    channel VARCHAR(32) NOT NULL DEFAULT 'email',
+    recipient VARCHAR(2048) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    html_body MEDIUMTEXT NOT NULL,
+    text_body MEDIUMTEXT NOT NULL,
+    payload MEDIUMTEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    next_attempt_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    INDEX idx_notifications_due (status, next_attempt_at),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+    );
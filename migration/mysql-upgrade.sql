@@ -0,0 +1,248 @@
+-- Brings a database created with an older mysql-init.sql up to date. Run it
+-- with the consumers stopped. Every step checks whether it is needed first,
+-- so the script can be run again, or on a database created by a newer
+-- mysql-init.sql.
+
+-- adds a column unless it is already there, databases created by different
+-- versions of mysql-init.sql start from different columns
+DROP PROCEDURE IF EXISTS add_column_if_missing;
+DELIMITER //
+CREATE PROCEDURE add_column_if_missing(p_table VARCHAR(64), p_column VARCHAR(64), p_definition TEXT)
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.COLUMNS
+                   WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = p_table AND COLUMN_NAME = p_column) THEN
+        SET @ddl = CONCAT('ALTER TABLE ', p_table, ' ADD COLUMN ', p_column, ' ', p_definition);
+        PREPARE statement FROM @ddl;
+        EXECUTE statement;
+        DEALLOCATE PREPARE statement;
+    END IF;
+END //
+DELIMITER ;
+
+-- adds an index unless one with the name is already there, p_definition is
+-- the part after ADD, such as 'UNIQUE INDEX idx_name (column)'
+DROP PROCEDURE IF EXISTS add_index_if_missing;
+DELIMITER //
+CREATE PROCEDURE add_index_if_missing(p_table VARCHAR(64), p_index VARCHAR(64), p_definition TEXT)
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.STATISTICS
+                   WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = p_table AND INDEX_NAME = p_index) THEN
+        SET @ddl = CONCAT('ALTER TABLE ', p_table, ' ADD ', p_definition);
+        PREPARE statement FROM @ddl;
+        EXECUTE statement;
+        DEALLOCATE PREPARE statement;
+    END IF;
+END //
+DELIMITER ;
+
+-- language of the summaries
+CALL add_column_if_missing('accounts', 'locale', 'VARCHAR(16) NOT NULL DEFAULT ''en'' AFTER email');
+
+-- scheduled summaries, only active accounts get them
+CALL add_column_if_missing('accounts', 'status', 'VARCHAR(32) NOT NULL DEFAULT ''active'' AFTER locale');
+
+CREATE TABLE IF NOT EXISTS summary_deliveries (
+    account_id VARCHAR(255) NOT NULL,
+    period VARCHAR(32) NOT NULL,
+    sent_at DATETIME NOT NULL,
+    PRIMARY KEY(account_id, period),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+    );
+
+-- outbox of the summaries to deliver
+CREATE TABLE IF NOT EXISTS notifications (
+    id VARCHAR(36) PRIMARY KEY,
+    account_id VARCHAR(255) NOT NULL,
+    recipient VARCHAR(250) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    html_body MEDIUMTEXT NOT NULL,
+    text_body MEDIUMTEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NULL,
+    next_attempt_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    INDEX idx_notifications_due (status, next_attempt_at),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+    );
+
+-- notification channels, existing accounts keep the email
+CALL add_column_if_missing('accounts', 'channels', 'VARCHAR(255) NOT NULL DEFAULT ''email'' AFTER status');
+CALL add_column_if_missing('accounts', 'webhook_url', 'VARCHAR(2048) NULL AFTER channels');
+CALL add_column_if_missing('notifications', 'channel', 'VARCHAR(32) NOT NULL DEFAULT ''email'' AFTER account_id');
+CALL add_column_if_missing('notifications', 'payload', 'MEDIUMTEXT NOT NULL AFTER text_body');
+ALTER TABLE notifications MODIFY recipient VARCHAR(2048) NOT NULL;
+
+-- transaction sizes per period, not known for movements registered before
+CALL add_column_if_missing('summaries', 'min_credit', 'DECIMAL(50, 3) NULL AFTER debit_qty');
+CALL add_column_if_missing('summaries', 'max_credit', 'DECIMAL(50, 3) NULL AFTER min_credit');
+CALL add_column_if_missing('summaries', 'min_debit', 'DECIMAL(50, 3) NULL AFTER max_credit');
+CALL add_column_if_missing('summaries', 'max_debit', 'DECIMAL(50, 3) NULL AFTER min_debit');
+
+-- The summaries are converted in two steps, each run only while the column
+-- it adds is missing: the data they rewrite cannot be converted twice.
+DROP PROCEDURE IF EXISTS upgrade_summaries;
+DELIMITER //
+CREATE PROCEDURE upgrade_summaries()
+BEGIN
+    DECLARE has_period_start, has_granularity BOOLEAN;
+    SET has_period_start = EXISTS (SELECT 1 FROM information_schema.COLUMNS
+                                   WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'summaries' AND COLUMN_NAME = 'period_start');
+    SET has_granularity = EXISTS (SELECT 1 FROM information_schema.COLUMNS
+                                  WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'summaries' AND COLUMN_NAME = 'granularity');
+
+    IF NOT has_period_start THEN
+        -- running balances per period
+        CALL add_column_if_missing('summaries', 'period_start', 'DATE NULL AFTER period');
+        CALL add_column_if_missing('summaries', 'opening_balance', 'DECIMAL(50, 3) NOT NULL DEFAULT 0 AFTER max_debit');
+        CALL add_column_if_missing('summaries', 'closing_balance', 'DECIMAL(50, 3) NOT NULL DEFAULT 0 AFTER opening_balance');
+
+        UPDATE summaries SET period_start = STR_TO_DATE(CONCAT(period, ' 1'), '%Y %M %d');
+
+        ALTER TABLE summaries MODIFY period_start DATE NOT NULL;
+        CALL add_index_if_missing('summaries', 'idx_summaries_start', 'INDEX idx_summaries_start (account_id, period_start)');
+
+        UPDATE summaries s
+            JOIN (SELECT account_id, period,
+                         SUM(credit + debit) OVER (PARTITION BY account_id ORDER BY period_start) AS closing
+                  FROM summaries) running
+            ON s.account_id = running.account_id AND s.period = running.period
+        SET s.closing_balance = running.closing,
+            s.opening_balance = running.closing - (s.credit + s.debit);
+    END IF;
+
+    IF NOT has_granularity THEN
+        -- typed period keys ("2023-10") and one projection per granularity
+        CALL add_column_if_missing('summaries', 'granularity', 'VARCHAR(16) NOT NULL DEFAULT ''month'' AFTER account_id');
+
+        UPDATE summaries SET period = DATE_FORMAT(period_start, '%Y-%m');
+
+        ALTER TABLE summaries
+            MODIFY granularity VARCHAR(16) NOT NULL,
+            MODIFY period VARCHAR(16) NOT NULL,
+            DROP PRIMARY KEY,
+            ADD PRIMARY KEY (account_id, granularity, period),
+            DROP INDEX idx_summaries_start,
+            ADD INDEX idx_summaries_start (account_id, granularity, period_start);
+
+        -- quarters and years are rolled up from the months
+        INSERT INTO summaries (account_id, granularity, period, period_start, credit, credit_qty, debit, debit_qty,
+                               min_credit, max_credit, min_debit, max_debit, opening_balance, closing_balance, last_updated)
+        SELECT account_id, 'quarter', CONCAT(YEAR(period_start), '-Q', QUARTER(period_start)),
+               MAKEDATE(YEAR(period_start), 1) + INTERVAL QUARTER(period_start) - 1 QUARTER,
+               SUM(credit), SUM(credit_qty), SUM(debit), SUM(debit_qty),
+               MIN(min_credit), MAX(max_credit), MIN(min_debit), MAX(max_debit),
+               SUM(credit + debit), SUM(credit + debit), MAX(last_updated)
+        FROM summaries
+        WHERE granularity = 'month'
+        GROUP BY account_id, YEAR(period_start), QUARTER(period_start);
+
+        INSERT INTO summaries (account_id, granularity, period, period_start, credit, credit_qty, debit, debit_qty,
+                               min_credit, max_credit, min_debit, max_debit, opening_balance, closing_balance, last_updated)
+        SELECT account_id, 'year', CAST(YEAR(period_start) AS CHAR), MAKEDATE(YEAR(period_start), 1),
+               SUM(credit), SUM(credit_qty), SUM(debit), SUM(debit_qty),
+               MIN(min_credit), MAX(max_credit), MIN(min_debit), MAX(max_debit),
+               SUM(credit + debit), SUM(credit + debit), MAX(last_updated)
+        FROM summaries
+        WHERE granularity = 'month'
+        GROUP BY account_id, YEAR(period_start);
+
+        UPDATE summaries s
+            JOIN (SELECT account_id, granularity, period,
+                         SUM(credit + debit) OVER (PARTITION BY account_id, granularity ORDER BY period_start) AS closing
+                  FROM summaries
+                  WHERE granularity IN ('quarter', 'year')) running
+            ON s.account_id = running.account_id AND s.granularity = running.granularity AND s.period = running.period
+        SET s.closing_balance = running.closing,
+            s.opening_balance = running.closing - (s.credit + s.debit);
+
+        -- days and weeks cannot be rebuilt from months. Every account gets one
+        -- empty row carrying its balance at the end of its last month, so the
+        -- periods registered after the upgrade open with the right balance.
+        INSERT INTO summaries (account_id, granularity, period, period_start, credit, credit_qty, debit, debit_qty,
+                               opening_balance, closing_balance, last_updated)
+        SELECT account_id, 'day', DATE_FORMAT(LAST_DAY(MAX(period_start)), '%Y-%m-%d'), LAST_DAY(MAX(period_start)),
+               0, 0, 0, 0, SUM(credit + debit), SUM(credit + debit), MAX(last_updated)
+        FROM summaries
+        WHERE granularity = 'month'
+        GROUP BY account_id;
+
+        INSERT INTO summaries (account_id, granularity, period, period_start, credit, credit_qty, debit, debit_qty,
+                               opening_balance, closing_balance, last_updated)
+        SELECT account_id, 'week', DATE_FORMAT(LAST_DAY(MAX(period_start)), '%x-W%v'),
+               LAST_DAY(MAX(period_start)) - INTERVAL WEEKDAY(LAST_DAY(MAX(period_start))) DAY,
+               0, 0, 0, 0, SUM(credit + debit), SUM(credit + debit), MAX(last_updated)
+        FROM summaries
+        WHERE granularity = 'month'
+        GROUP BY account_id;
+    END IF;
+END //
+DELIMITER ;
+
+CALL upgrade_summaries();
+DROP PROCEDURE upgrade_summaries;
+
+-- timezone periods are bucketed in, existing summaries were bucketed in UTC
+CALL add_column_if_missing('accounts', 'timezone', 'VARCHAR(64) NOT NULL DEFAULT ''UTC'' AFTER locale');
+
+-- categorization rules and spending by category, movements registered before
+-- are counted from the next ones on
+CREATE TABLE IF NOT EXISTS category_rules (
+    id VARCHAR(36) PRIMARY KEY,
+    account_id VARCHAR(255) NULL,
+    field VARCHAR(16) NOT NULL,
+    match_type VARCHAR(16) NOT NULL,
+    pattern VARCHAR(512) NOT NULL,
+    category VARCHAR(64) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    INDEX idx_category_rules_account (account_id, priority),
+    FOREIGN KEY (account_id) REFERENCES accounts(id) ON DELETE CASCADE
+    );
+
+CREATE TABLE IF NOT EXISTS category_summaries (
+    account_id VARCHAR(255) NOT NULL,
+    granularity VARCHAR(16) NOT NULL,
+    period VARCHAR(16) NOT NULL,
+    period_start DATE NOT NULL,
+    category VARCHAR(64) NOT NULL,
+    credit DECIMAL(50, 3) NOT NULL,
+    credit_qty INTEGER NOT NULL,
+    debit DECIMAL(50, 3) NOT NULL,
+    debit_qty INTEGER NOT NULL,
+    last_updated DATETIME NOT NULL,
+    PRIMARY KEY(account_id, granularity, period, category),
+    INDEX idx_category_summaries_start (account_id, granularity, period_start),
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+    );
+
+-- category each transaction was counted under, transactions registered
+-- before are reversed under the category the current rules give them
+CREATE TABLE IF NOT EXISTS transaction_categories (
+    event_id VARCHAR(36) PRIMARY KEY,
+    account_id VARCHAR(255) NOT NULL,
+    category VARCHAR(64) NOT NULL,
+    FOREIGN KEY (account_id) REFERENCES accounts(id)
+    );
+
+-- transfers between accounts, counted apart from credits and debits
+CALL add_column_if_missing('summaries', 'transfer_in', 'DECIMAL(50, 3) NOT NULL DEFAULT 0 AFTER debit_qty');
+CALL add_column_if_missing('summaries', 'transfer_in_qty', 'INTEGER NOT NULL DEFAULT 0 AFTER transfer_in');
+CALL add_column_if_missing('summaries', 'transfer_out', 'DECIMAL(50, 3) NOT NULL DEFAULT 0 AFTER transfer_in_qty');
+CALL add_column_if_missing('summaries', 'transfer_out_qty', 'INTEGER NOT NULL DEFAULT 0 AFTER transfer_out');
+
+-- overdraft policies, existing accounts keep accepting every debit
+CALL add_column_if_missing('accounts', 'overdraft_policy', 'VARCHAR(16) NOT NULL DEFAULT ''flag'' AFTER webhook_url');
+CALL add_column_if_missing('accounts', 'overdraft_limit', 'DECIMAL(50, 3) NOT NULL DEFAULT 0 AFTER overdraft_policy');
+
+-- account search, emails are unique and stored lowercased; duplicated emails
+-- have to be merged by hand before the unique index can be added
+UPDATE accounts SET email = LOWER(TRIM(email));
+CALL add_index_if_missing('accounts', 'idx_accounts_email', 'UNIQUE INDEX idx_accounts_email (email)');
+CALL add_index_if_missing('accounts', 'idx_accounts_name', 'INDEX idx_accounts_name (name)');
+CALL add_index_if_missing('accounts', 'idx_accounts_status', 'INDEX idx_accounts_status (status)');
+
+DROP PROCEDURE add_column_if_missing;
+DROP PROCEDURE add_index_if_missing;
@@ -0,0 +1,137 @@
+// Package cron parses standard five field cron expressions
+// (minute hour day-of-month month day-of-week) and computes activation times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	Schedule struct {
+		minute, hour, dom, month, dow field
+		domAny, dowAny                bool
+	}
+
+	field map[int]bool
+)
+
+var bounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// Parse accepts "*", single values, ranges ("1-5"), lists ("1,15") and steps
+// ("*/15", "0-30/10") in every field. Day of week 7 is treated as Sunday. A
+// day field starting with "*", such as "*/2", counts as unrestricted when
+// the other day field is restricted, as in Vixie cron.
+func Parse(spec string) (Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return Schedule{}, fmt.Errorf("cron: expected 5 fields in %q", spec)
+	}
+
+	fields := make([]field, 5)
+	for i, part := range parts {
+		max := bounds[i][1]
+		if i == 4 {
+			max = 7
+		}
+		f, err := parseField(part, bounds[i][0], max)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("cron: field %d of %q: %w", i+1, spec, err)
+		}
+		fields[i] = f
+	}
+	if fields[4][7] {
+		fields[4][0] = true
+		delete(fields[4], 7)
+	}
+
+	return Schedule{
+		minute: fields[0],
+		hour:   fields[1],
+		dom:    fields[2],
+		month:  fields[3],
+		dow:    fields[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseField(value string, min, max int) (field, error) {
+	f := field{}
+	for _, item := range strings.Split(value, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("bad step %q", item)
+			}
+			item = item[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			bounds := strings.SplitN(item, "-", 2)
+			var errLo, errHi error
+			lo, errLo = strconv.Atoi(bounds[0])
+			hi, errHi = strconv.Atoi(bounds[1])
+			if errLo != nil || errHi != nil {
+				return nil, fmt.Errorf("bad range %q", item)
+			}
+		default:
+			n, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, fmt.Errorf("bad value %q", item)
+			}
+			lo, hi = n, n
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q out of range %d-%d", item, min, max)
+		}
+		for n := lo; n <= hi; n += step {
+			f[n] = true
+		}
+	}
+	return f, nil
+}
+
+// Matches reports whether t, truncated to the minute, is an activation time.
+func (s Schedule) Matches(t time.Time) bool {
+	return s.minute[t.Minute()] && s.hour[t.Hour()] && s.month[int(t.Month())] && s.day(t)
+}
+
+func (s Schedule) day(t time.Time) bool {
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	// Like Vixie cron, when both day fields are restricted either may match.
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first activation time strictly after t, in t's location.
+// It gives up after five years, which only happens for impossible dates
+// such as "0 0 30 2 *", returning the zero time.
+func (s Schedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		year, month, day := next.Date()
+		switch {
+		case !s.month[int(month)]:
+			next = time.Date(year, month+1, 1, 0, 0, 0, 0, next.Location())
+		case !s.day(next):
+			next = time.Date(year, month, day+1, 0, 0, 0, 0, next.Location())
+		case !s.hour[next.Hour()]:
+			next = time.Date(year, month, day, next.Hour()+1, 0, 0, 0, next.Location())
+		case !s.minute[next.Minute()]:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
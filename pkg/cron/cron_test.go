@@ -0,0 +1,172 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{spec: "", err: "expected 5 fields"},
+		{spec: "0 8 1 *", err: "expected 5 fields"},
+		{spec: "0 8 1 * * *", err: "expected 5 fields"},
+		{spec: "60 8 1 * *", err: "field 1"},
+		{spec: "0 24 1 * *", err: "field 2"},
+		{spec: "0 8 0 * *", err: "field 3"},
+		{spec: "0 8 32 * *", err: "field 3"},
+		{spec: "0 8 1 13 *", err: "field 4"},
+		{spec: "0 8 1 * 8", err: "field 5"},
+		{spec: "0 8 10-5 * *", err: "out of range"},
+		{spec: "*/0 8 1 * *", err: "bad step"},
+		{spec: "*/x 8 1 * *", err: "bad step"},
+		{spec: "0-30/-5 8 1 * *", err: "bad step"},
+		{spec: "a 8 1 * *", err: "bad value"},
+		{spec: "1-b 8 1 * *", err: "bad range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Parse(%q) = %v, want an error containing %q", tt.spec, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   int
+		max   int
+		want  []int
+	}{
+		{name: "any", value: "*", min: 1, max: 5, want: []int{1, 2, 3, 4, 5}},
+		{name: "value", value: "7", min: 0, max: 59, want: []int{7}},
+		{name: "list", value: "1,15,30", min: 1, max: 31, want: []int{1, 15, 30}},
+		{name: "range", value: "9-12", min: 0, max: 23, want: []int{9, 10, 11, 12}},
+		{name: "step", value: "*/15", min: 0, max: 59, want: []int{0, 15, 30, 45}},
+		{name: "step from one", value: "*/10", min: 1, max: 31, want: []int{1, 11, 21, 31}},
+		{name: "range with step", value: "0-30/10", min: 0, max: 59, want: []int{0, 10, 20, 30}},
+		{name: "list of ranges", value: "1-2,10-20/5", min: 0, max: 23, want: []int{1, 2, 10, 15, 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseField(tt.value, tt.min, tt.max)
+			if err != nil {
+				t.Fatalf("parseField(%q): %v", tt.value, err)
+			}
+			var got []int
+			for n := tt.min; n <= tt.max; n++ {
+				if f[n] {
+					got = append(got, n)
+				}
+			}
+			if len(got) != len(tt.want) || len(got) != len(f) {
+				t.Fatalf("parseField(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("parseField(%q) = %v, want %v", tt.value, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchesDays(t *testing.T) {
+	// 2023-10-01 is a Sunday, 2023-10-13 a Friday
+	day := func(d int) time.Time { return time.Date(2023, 10, d, 8, 0, 0, 0, time.UTC) }
+	var october []int
+	for d := 1; d <= 31; d++ {
+		october = append(october, d)
+	}
+	tests := []struct {
+		name string
+		spec string
+		days []int
+	}{
+		{name: "both unrestricted", spec: "0 8 * * *", days: october},
+		{name: "day of month", spec: "0 8 13 * *", days: []int{13}},
+		{name: "day of week", spec: "0 8 * * 5", days: []int{6, 13, 20, 27}},
+		{name: "sunday as 7", spec: "0 8 * * 7", days: []int{1, 8, 15, 22, 29}},
+		{name: "both restricted match with or", spec: "0 8 13 * 1", days: []int{2, 9, 13, 16, 23, 30}},
+		{name: "step on day of month is unrestricted", spec: "0 8 */10 * 3", days: []int{11}},
+		{name: "step on day of week is unrestricted", spec: "0 8 1-7 * */2", days: []int{1, 3, 5, 7}},
+		{name: "step on day of month alone", spec: "0 8 */10 * *", days: []int{1, 11, 21, 31}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			want := make(map[int]bool)
+			for _, d := range tt.days {
+				want[d] = true
+			}
+			for d := 1; d <= 31; d++ {
+				if got := schedule.Matches(day(d)); got != want[d] {
+					t.Errorf("Matches(2023-10-%02d) = %v, want %v", d, got, want[d])
+				}
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{name: "same hour", spec: "*/15 * * * *", from: at(2023, 10, 5, 12, 7), want: at(2023, 10, 5, 12, 15)},
+		{name: "strictly after", spec: "30 12 * * *", from: at(2023, 10, 5, 12, 30), want: at(2023, 10, 6, 12, 30)},
+		{name: "seconds are dropped", spec: "* * * * *", from: at(2023, 10, 5, 12, 7).Add(42 * time.Second), want: at(2023, 10, 5, 12, 8)},
+		{name: "next day", spec: "0 8 * * *", from: at(2023, 10, 5, 9, 0), want: at(2023, 10, 6, 8, 0)},
+		{name: "month rollover", spec: "0 8 1 * *", from: at(2023, 10, 5, 9, 0), want: at(2023, 11, 1, 8, 0)},
+		{name: "year rollover", spec: "0 8 1 * *", from: at(2023, 12, 1, 8, 0), want: at(2024, 1, 1, 8, 0)},
+		{name: "last minute of the year", spec: "* * * * *", from: at(2023, 12, 31, 23, 59), want: at(2024, 1, 1, 0, 0)},
+		{name: "31st skips short months", spec: "0 0 31 * *", from: at(2023, 3, 31, 0, 0), want: at(2023, 5, 31, 0, 0)},
+		{name: "31st across the year", spec: "0 0 31 * *", from: at(2023, 12, 31, 0, 0), want: at(2024, 1, 31, 0, 0)},
+		{name: "29 february", spec: "0 0 29 2 *", from: at(2023, 3, 1, 0, 0), want: at(2024, 2, 29, 0, 0)},
+		{name: "month of the year", spec: "0 0 1 6 *", from: at(2023, 7, 1, 0, 0), want: at(2024, 6, 1, 0, 0)},
+		{name: "day of week", spec: "0 9 * * 1", from: at(2023, 10, 5, 12, 0), want: at(2023, 10, 9, 9, 0)},
+		{name: "impossible date", spec: "0 0 30 2 *", from: at(2023, 1, 1, 0, 0), want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Fatalf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+	location, err := time.LoadLocation("America/Argentina/Buenos_Aires")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+	schedule, err := Parse("0 8 1 * *")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	from := time.Date(2023, 10, 31, 22, 0, 0, 0, location)
+	want := time.Date(2023, 11, 1, 8, 0, 0, 0, location)
+	if got := schedule.Next(from); !got.Equal(want) || got.Location() != location {
+		t.Fatalf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package email
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	TLSMode  string
+	AuthMode string
+)
+
+const (
+	TLSNone     TLSMode = "none"
+	TLSStartTLS TLSMode = "starttls"
+	TLSImplicit TLSMode = "tls"
+
+	AuthNone    AuthMode = "none"
+	AuthPlain   AuthMode = "plain"
+	AuthLogin   AuthMode = "login"
+	AuthCRAMMD5 AuthMode = "cram-md5"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// ConfigFromEnv builds the SMTP configuration from SMTP_* variables read with
+// getenv:
+//
+//	SMTP_HOST, SMTP_PORT                 server address, localhost:25 by default
+//	SMTP_TLS                             none (default), starttls or tls
+//	SMTP_CA_FILE                         PEM bundle used to verify the server
+//	SMTP_TLS_INSECURE_SKIP_VERIFY        true disables certificate checks
+//	SMTP_AUTH                            none, plain, login or cram-md5; plain
+//	                                     when SMTP_USERNAME is set, none otherwise
+//	SMTP_USERNAME, SMTP_PASSWORD         credentials
+//	SMTP_FROM_ADDRESS, SMTP_FROM_NAME    sender of every message
+//	SMTP_REPLY_TO                        optional Reply-To address
+//	SMTP_TIMEOUT                         dial and session timeout, 30s by default
+func ConfigFromEnv(getenv func(string) string) (SMTPConfig, error) {
+	config := SMTPConfig{
+		Host:     getenv("SMTP_HOST"),
+		Port:     25,
+		Username: getenv("SMTP_USERNAME"),
+		Password: getenv("SMTP_PASSWORD"),
+		TLS:      TLSMode(strings.ToLower(getenv("SMTP_TLS"))),
+		Auth:     AuthMode(strings.ToLower(getenv("SMTP_AUTH"))),
+		Timeout:  defaultTimeout,
+	}
+	if config.Host == "" {
+		config.Host = "localhost"
+	}
+
+	if port := getenv("SMTP_PORT"); port != "" {
+		parsed, err := strconv.Atoi(port)
+		if err != nil || parsed <= 0 || parsed > 65535 {
+			return SMTPConfig{}, fmt.Errorf("smtp: bad SMTP_PORT %q", port)
+		}
+		config.Port = parsed
+	}
+
+	if timeout := getenv("SMTP_TIMEOUT"); timeout != "" {
+		parsed, err := time.ParseDuration(timeout)
+		if err != nil {
+			return SMTPConfig{}, fmt.Errorf("smtp: bad SMTP_TIMEOUT: %w", err)
+		}
+		config.Timeout = parsed
+	}
+
+	switch config.TLS {
+	case "":
+		config.TLS = TLSNone
+	case TLSNone, TLSStartTLS, TLSImplicit:
+	default:
+		return SMTPConfig{}, fmt.Errorf("smtp: bad SMTP_TLS %q", config.TLS)
+	}
+
+	switch config.Auth {
+	case "":
+		config.Auth = AuthNone
+		if config.Username != "" {
+			config.Auth = AuthPlain
+		}
+	case AuthNone, AuthPlain, AuthLogin, AuthCRAMMD5:
+	default:
+		return SMTPConfig{}, fmt.Errorf("smtp: bad SMTP_AUTH %q", config.Auth)
+	}
+
+	config.TLSConfig = &tls.Config{
+		ServerName:         config.Host,
+		InsecureSkipVerify: getenv("SMTP_TLS_INSECURE_SKIP_VERIFY") == "true",
+	}
+	if caFile := getenv("SMTP_CA_FILE"); caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return SMTPConfig{}, fmt.Errorf("smtp: reading SMTP_CA_FILE: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return SMTPConfig{}, fmt.Errorf("smtp: no certificates found in %s", caFile)
+		}
+		config.TLSConfig.RootCAs = pool
+	}
+
+	fromAddress := getenv("SMTP_FROM_ADDRESS")
+	if fromAddress == "" {
+		fromAddress = "no-reply@" + config.Host
+	}
+	from, err := mail.ParseAddress(fromAddress)
+	if err != nil {
+		return SMTPConfig{}, fmt.Errorf("smtp: bad SMTP_FROM_ADDRESS: %w", err)
+	}
+	if name := getenv("SMTP_FROM_NAME"); name != "" {
+		from.Name = name
+	}
+	config.From = *from
+
+	if replyTo := getenv("SMTP_REPLY_TO"); replyTo != "" {
+		address, err := mail.ParseAddress(replyTo)
+		if err != nil {
+			return SMTPConfig{}, fmt.Errorf("smtp: bad SMTP_REPLY_TO: %w", err)
+		}
+		config.ReplyTo = address
+	}
+	return config, nil
+}
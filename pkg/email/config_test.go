@@ -0,0 +1,96 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		check   func(*testing.T, SMTPConfig)
+		wantErr string
+	}{
+		{name: "defaults", check: func(t *testing.T, c SMTPConfig) {
+			if c.Host != "localhost" || c.Port != 25 || c.TLS != TLSNone || c.Auth != AuthNone || c.Timeout != 30*time.Second {
+				t.Errorf("got %s:%d tls %s auth %s timeout %s", c.Host, c.Port, c.TLS, c.Auth, c.Timeout)
+			}
+			if c.From.Address != "no-reply@localhost" || c.ReplyTo != nil {
+				t.Errorf("got from %v reply to %v", c.From, c.ReplyTo)
+			}
+			if c.TLSConfig.ServerName != "localhost" || c.TLSConfig.InsecureSkipVerify {
+				t.Errorf("got tls config for %q, skip verify %v", c.TLSConfig.ServerName, c.TLSConfig.InsecureSkipVerify)
+			}
+		}},
+		{name: "server", env: map[string]string{
+			"SMTP_HOST": "smtp.example.com", "SMTP_PORT": "465", "SMTP_TIMEOUT": "5s",
+			"SMTP_FROM_ADDRESS": "reports@example.com", "SMTP_FROM_NAME": "Reports", "SMTP_REPLY_TO": "help@example.com",
+		}, check: func(t *testing.T, c SMTPConfig) {
+			if c.Host != "smtp.example.com" || c.Port != 465 || c.Timeout != 5*time.Second || c.TLSConfig.ServerName != "smtp.example.com" {
+				t.Errorf("got %s:%d timeout %s server name %q", c.Host, c.Port, c.Timeout, c.TLSConfig.ServerName)
+			}
+			if c.From.String() != `"Reports" <reports@example.com>` || c.ReplyTo == nil || c.ReplyTo.Address != "help@example.com" {
+				t.Errorf("got from %v reply to %v", c.From, c.ReplyTo)
+			}
+		}},
+		{name: "bad port", env: map[string]string{"SMTP_PORT": "smtp"}, wantErr: "SMTP_PORT"},
+		{name: "port zero", env: map[string]string{"SMTP_PORT": "0"}, wantErr: "SMTP_PORT"},
+		{name: "port out of range", env: map[string]string{"SMTP_PORT": "65536"}, wantErr: "SMTP_PORT"},
+		{name: "bad timeout", env: map[string]string{"SMTP_TIMEOUT": "30"}, wantErr: "SMTP_TIMEOUT"},
+		{name: "starttls", env: map[string]string{"SMTP_TLS": "starttls"}, check: func(t *testing.T, c SMTPConfig) {
+			if c.TLS != TLSStartTLS {
+				t.Errorf("got tls %s", c.TLS)
+			}
+		}},
+		{name: "implicit tls in capitals", env: map[string]string{"SMTP_TLS": "TLS"}, check: func(t *testing.T, c SMTPConfig) {
+			if c.TLS != TLSImplicit {
+				t.Errorf("got tls %s", c.TLS)
+			}
+		}},
+		{name: "unknown tls mode", env: map[string]string{"SMTP_TLS": "ssl"}, wantErr: "SMTP_TLS"},
+		{name: "insecure", env: map[string]string{"SMTP_TLS_INSECURE_SKIP_VERIFY": "true"}, check: func(t *testing.T, c SMTPConfig) {
+			if !c.TLSConfig.InsecureSkipVerify {
+				t.Errorf("certificates are still verified")
+			}
+		}},
+		{name: "missing ca file", env: map[string]string{"SMTP_CA_FILE": filepath.Join(t.TempDir(), "missing.pem")}, wantErr: "SMTP_CA_FILE"},
+		{name: "ca file without certificates", env: map[string]string{"SMTP_CA_FILE": notPEM}, wantErr: "no certificates"},
+		{name: "plain auth with a username", env: map[string]string{"SMTP_USERNAME": "reports"}, check: func(t *testing.T, c SMTPConfig) {
+			if c.Auth != AuthPlain {
+				t.Errorf("got auth %s", c.Auth)
+			}
+		}},
+		{name: "login auth", env: map[string]string{"SMTP_USERNAME": "reports", "SMTP_AUTH": "LOGIN"}, check: func(t *testing.T, c SMTPConfig) {
+			if c.Auth != AuthLogin {
+				t.Errorf("got auth %s", c.Auth)
+			}
+		}},
+		{name: "unknown auth", env: map[string]string{"SMTP_AUTH": "xoauth2"}, wantErr: "SMTP_AUTH"},
+		{name: "bad from", env: map[string]string{"SMTP_FROM_ADDRESS": "reports"}, wantErr: "SMTP_FROM_ADDRESS"},
+		{name: "bad reply to", env: map[string]string{"SMTP_REPLY_TO": "help@"}, wantErr: "SMTP_REPLY_TO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ConfigFromEnv(func(key string) string { return tt.env[key] })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ConfigFromEnv() = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConfigFromEnv() = %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+// Package emailtest provides a fake SMTP server for tests, listening on the
+// loopback interface. It speaks enough ESMTP for net/smtp clients: STARTTLS
+// or implicit TLS, AUTH PLAIN and LOGIN, and records every message received.
+package emailtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type (
+	Options struct {
+		// StartTLS advertises STARTTLS, ImplicitTLS expects TLS from the
+		// first byte.
+		StartTLS    bool
+		ImplicitTLS bool
+		// Username and Password, when set, are the only credentials
+		// accepted and AUTH is advertised.
+		Username, Password string
+	}
+
+	// Message is what the server received in one mail transaction. Data
+	// keeps the lines of the message joined with "\n".
+	Message struct {
+		From string
+		To   []string
+		Data string
+	}
+
+	Server struct {
+		Host string
+		Port int
+
+		options  Options
+		listener net.Listener
+		tls      *tls.Config
+		roots    *x509.CertPool
+		sessions sync.WaitGroup
+		close    sync.Once
+
+		mu       sync.Mutex
+		messages []Message
+	}
+)
+
+// NewServer starts a server, closed when the test ends.
+func NewServer(t testing.TB, options Options) *Server {
+	t.Helper()
+	certificate, roots := selfSigned(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("emailtest: listen: %v", err)
+	}
+
+	address := listener.Addr().(*net.TCPAddr)
+	s := &Server{
+		Host:     address.IP.String(),
+		Port:     address.Port,
+		options:  options,
+		listener: listener,
+		tls:      &tls.Config{Certificates: []tls.Certificate{certificate}},
+		roots:    roots,
+	}
+	s.sessions.Add(1)
+	go s.accept()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close stops listening and waits for the sessions in progress.
+func (s *Server) Close() {
+	s.close.Do(func() {
+		_ = s.listener.Close()
+		s.sessions.Wait()
+	})
+}
+
+// Addr is the host:port the server listens on.
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// RootCAs trusts the certificate of the server.
+func (s *Server) RootCAs() *x509.CertPool {
+	return s.roots
+}
+
+// Messages returns the messages received so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+func (s *Server) accept() {
+	defer s.sessions.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.sessions.Add(1)
+		go func() {
+			defer s.sessions.Done()
+			s.serve(conn)
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	secure := s.options.ImplicitTLS
+	if secure {
+		conn = tls.Server(conn, s.tls)
+	}
+	text := textproto.NewConn(conn)
+	reply := func(line string) bool { return text.PrintfLine("%s", line) == nil }
+
+	if !reply("220 emailtest ESMTP") {
+		return
+	}
+	var message Message
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			extensions := []string{"emailtest", "8BITMIME"}
+			if s.options.StartTLS && !secure {
+				extensions = append(extensions, "STARTTLS")
+			}
+			if s.options.Username != "" {
+				extensions = append(extensions, "AUTH PLAIN LOGIN")
+			}
+			for i, extension := range extensions {
+				separator := "-"
+				if i == len(extensions)-1 {
+					separator = " "
+				}
+				if !reply("250" + separator + extension) {
+					return
+				}
+			}
+		case "STARTTLS":
+			if !s.options.StartTLS || secure {
+				reply("502 5.5.1 not available")
+				continue
+			}
+			if !reply("220 2.0.0 ready") {
+				return
+			}
+			tlsConn := tls.Server(conn, s.tls)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, secure = tlsConn, true
+			text = textproto.NewConn(conn)
+		case "AUTH":
+			if s.options.Username == "" {
+				reply("502 5.5.1 not available")
+				continue
+			}
+			username, password, ok := s.credentials(text, arg)
+			if !ok {
+				return
+			}
+			if username != s.options.Username || password != s.options.Password {
+				reply("535 5.7.8 bad credentials")
+				continue
+			}
+			reply("235 2.7.0 authenticated")
+		case "NOOP", "RSET":
+			reply("250 2.0.0 ok")
+		case "MAIL":
+			message = Message{From: address(arg)}
+			reply("250 2.1.0 ok")
+		case "RCPT":
+			message.To = append(message.To, address(arg))
+			reply("250 2.1.5 ok")
+		case "DATA":
+			if !reply("354 end with <CRLF>.<CRLF>") {
+				return
+			}
+			data, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			message.Data = strings.Join(data, "\n")
+			s.mu.Lock()
+			s.messages = append(s.messages, message)
+			s.mu.Unlock()
+			reply("250 2.0.0 queued")
+		case "QUIT":
+			reply("221 2.0.0 bye")
+			return
+		default:
+			reply("502 5.5.2 unknown command")
+		}
+	}
+}
+
+// credentials reads the PLAIN or LOGIN exchange started by "AUTH arg", ok is
+// false when the session must end.
+func (s *Server) credentials(text *textproto.Conn, arg string) (username, password string, ok bool) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+	challenge := func(prompt string) (string, bool) {
+		if text.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(prompt))) != nil {
+			return "", false
+		}
+		line, err := text.ReadLine()
+		if err != nil {
+			return "", false
+		}
+		decoded, err := base64.StdEncoding.DecodeString(line)
+		return string(decoded), err == nil
+	}
+
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		// net/smtp always sends the initial response
+		response, err := base64.StdEncoding.DecodeString(initial)
+		parts := strings.Split(string(response), "\x00")
+		if err != nil || len(parts) != 3 {
+			return "", "", true
+		}
+		return parts[1], parts[2], true
+	case "LOGIN":
+		if username, ok = challenge("Username:"); !ok {
+			return "", "", false
+		}
+		password, ok = challenge("Password:")
+		return username, password, ok
+	default:
+		return "", "", false
+	}
+}
+
+// address returns the address of "FROM:<a@b>" or "TO:<a@b>".
+func address(arg string) string {
+	_, value, _ := strings.Cut(arg, ":")
+	value, _, _ = strings.Cut(strings.TrimSpace(value), " ")
+	return strings.Trim(value, "<>")
+}
+
+func selfSigned(t testing.TB) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("emailtest: key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "emailtest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{"localhost"},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("emailtest: certificate: %v", err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("emailtest: certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: parsed}, roots
+}
@@ -0,0 +1,105 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+type (
+	// Message is a complete email ready to be encoded and sent. It is built
+	// for every send and passed by value to the transport, nothing about a
+	// message is kept between sends.
+	Message struct {
+		From    mail.Address
+		ReplyTo *mail.Address
+		To      []mail.Address
+		Subject string
+		HTML    string
+		Text    string
+	}
+)
+
+// Recipients returns the bare addresses used for the SMTP envelope.
+func (m Message) Recipients() []string {
+	rcpts := make([]string, 0, len(m.To))
+	for _, to := range m.To {
+		rcpts = append(rcpts, to.Address)
+	}
+	return rcpts
+}
+
+// Bytes encodes the message as RFC 5322. When both bodies are set they are
+// sent as multipart/alternative with the plain text first.
+func (m Message) Bytes() []byte {
+	var buf bytes.Buffer
+
+	to := make([]string, 0, len(m.To))
+	for _, address := range m.To {
+		to = append(to, address.String())
+	}
+
+	writeHeader(&buf, "From", m.From.String())
+	writeHeader(&buf, "To", strings.Join(to, ", "))
+	if m.ReplyTo != nil {
+		writeHeader(&buf, "Reply-To", m.ReplyTo.String())
+	}
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	writeHeader(&buf, "Date", time.Now().Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", fmt.Sprintf("<%s@%s>", randomID(), domainOf(m.From.Address)))
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	switch {
+	case m.HTML != "" && m.Text != "":
+		boundary := randomID()
+		writeHeader(&buf, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=%q", boundary))
+		buf.WriteString("\r\n")
+		writePart(&buf, boundary, "text/plain; charset=utf-8", m.Text)
+		writePart(&buf, boundary, "text/html; charset=utf-8", m.HTML)
+		fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	case m.HTML != "":
+		writeBody(&buf, "text/html; charset=utf-8", m.HTML)
+	default:
+		writeBody(&buf, "text/plain; charset=utf-8", m.Text)
+	}
+	return buf.Bytes()
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+}
+
+func writePart(buf *bytes.Buffer, boundary, contentType, body string) {
+	fmt.Fprintf(buf, "--%s\r\n", boundary)
+	writeBody(buf, contentType, body)
+	buf.WriteString("\r\n")
+}
+
+func writeBody(buf *bytes.Buffer, contentType, body string) {
+	writeHeader(buf, "Content-Type", contentType)
+	writeHeader(buf, "Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(buf)
+	_, _ = qp.Write([]byte(body))
+	_ = qp.Close()
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func domainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
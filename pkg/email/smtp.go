@@ -0,0 +1,196 @@
+// Package email builds MIME messages and delivers them over SMTP.
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type (
+	SMTPConfig struct {
+		Host      string
+		Port      int
+		Username  string
+		Password  string
+		Auth      AuthMode
+		TLS       TLSMode
+		TLSConfig *tls.Config
+		Timeout   time.Duration
+
+		// From and ReplyTo are applied to messages that do not set them.
+		From    mail.Address
+		ReplyTo *mail.Address
+	}
+
+	// SMTPTransport opens a new connection for every message and keeps no
+	// per-message state, so one transport can be shared by any number of
+	// concurrent senders.
+	SMTPTransport struct {
+		config SMTPConfig
+	}
+
+	loginAuth struct {
+		username, password string
+	}
+)
+
+// NewSMTPTransport copies the TLS configuration, later changes made by the
+// caller do not reach connections opened by the transport.
+func NewSMTPTransport(config SMTPConfig) *SMTPTransport {
+	if config.TLSConfig != nil {
+		config.TLSConfig = config.TLSConfig.Clone()
+	}
+	if config.ReplyTo != nil {
+		replyTo := *config.ReplyTo
+		config.ReplyTo = &replyTo
+	}
+	return &SMTPTransport{config: config}
+}
+
+// Send delivers msg. The message is taken by value and its recipients are
+// copied before use, so callers may reuse or change theirs once Send returns
+// or while it runs.
+func (t *SMTPTransport) Send(ctx context.Context, msg Message) error {
+	msg.To = append([]mail.Address(nil), msg.To...)
+	if msg.From.Address == "" {
+		msg.From = t.config.From
+	}
+	if msg.ReplyTo == nil {
+		msg.ReplyTo = t.config.ReplyTo
+	}
+
+	client, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err = client.Mail(msg.From.Address); err != nil {
+		return fmt.Errorf("smtp mail from: %w", err)
+	}
+	for _, rcpt := range msg.Recipients() {
+		if err = client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("smtp rcpt to %s: %w", rcpt, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp data: %w", err)
+	}
+	if _, err = writer.Write(msg.Bytes()); err != nil {
+		return fmt.Errorf("smtp write: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return fmt.Errorf("smtp data close: %w", err)
+	}
+	return client.Quit()
+}
+
+// Check connects, negotiates TLS and authenticates without sending anything,
+// so a wrong configuration is reported at startup instead of on first send.
+func (t *SMTPTransport) Check(ctx context.Context) error {
+	client, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err = client.Noop(); err != nil {
+		return fmt.Errorf("smtp noop: %w", err)
+	}
+	return client.Quit()
+}
+
+func (t *SMTPTransport) dial(ctx context.Context) (*smtp.Client, error) {
+	if t.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.config.Timeout)
+		defer cancel()
+	}
+
+	address := net.JoinHostPort(t.config.Host, strconv.Itoa(t.config.Port))
+	var (
+		conn net.Conn
+		err  error
+	)
+	if t.config.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{Config: t.config.TLSConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("smtp dial %s: %w", address, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, t.config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("smtp client: %w", err)
+	}
+
+	if err = t.negotiate(client); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (t *SMTPTransport) negotiate(client *smtp.Client) error {
+	if t.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp: server does not support STARTTLS")
+		}
+		if err := client.StartTLS(t.config.TLSConfig); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+
+	var auth smtp.Auth
+	switch t.config.Auth {
+	case AuthPlain:
+		auth = smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host)
+	case AuthLogin:
+		auth = loginAuth{username: t.config.Username, password: t.config.Password}
+	case AuthCRAMMD5:
+		auth = smtp.CRAMMD5Auth(t.config.Username, t.config.Password)
+	default:
+		return nil
+	}
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("smtp auth: %w", err)
+	}
+	return nil
+}
+
+// loginAuth implements the LOGIN mechanism, not provided by net/smtp but still
+// required by some providers.
+func (a loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && server.Name != "localhost" && server.Name != "127.0.0.1" {
+		return "", nil, errors.New("smtp: LOGIN auth requires an encrypted connection")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	switch string(fromServer) {
+	case "Username:", "User Name\x00":
+		return []byte(a.username), nil
+	case "Password:", "Password\x00":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("smtp: unexpected LOGIN challenge %q", fromServer)
+	}
+}
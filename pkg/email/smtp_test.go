@@ -0,0 +1,168 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/castiglionimax/process-csv/pkg/email/emailtest"
+)
+
+func TestCheck(t *testing.T) {
+	const username, password = "reports", "s3cret"
+	tests := []struct {
+		name    string
+		server  emailtest.Options
+		config  func(*SMTPConfig)
+		wantErr string
+	}{
+		{name: "plain connection"},
+		{name: "starttls", server: emailtest.Options{StartTLS: true},
+			config: func(c *SMTPConfig) { c.TLS = TLSStartTLS }},
+		{name: "starttls not offered",
+			config: func(c *SMTPConfig) { c.TLS = TLSStartTLS }, wantErr: "does not support STARTTLS"},
+		{name: "implicit tls", server: emailtest.Options{ImplicitTLS: true},
+			config: func(c *SMTPConfig) { c.TLS = TLSImplicit }},
+		{name: "untrusted certificate", server: emailtest.Options{ImplicitTLS: true},
+			config: func(c *SMTPConfig) { c.TLS, c.TLSConfig.RootCAs = TLSImplicit, nil }, wantErr: "certificate"},
+		{name: "plain auth", server: emailtest.Options{StartTLS: true, Username: username, Password: password},
+			config: func(c *SMTPConfig) {
+				c.TLS, c.Auth, c.Username, c.Password = TLSStartTLS, AuthPlain, username, password
+			}},
+		{name: "login auth", server: emailtest.Options{StartTLS: true, Username: username, Password: password},
+			config: func(c *SMTPConfig) {
+				c.TLS, c.Auth, c.Username, c.Password = TLSStartTLS, AuthLogin, username, password
+			}},
+		{name: "wrong password", server: emailtest.Options{StartTLS: true, Username: username, Password: password},
+			config:  func(c *SMTPConfig) { c.TLS, c.Auth, c.Username, c.Password = TLSStartTLS, AuthPlain, username, "guess" },
+			wantErr: "smtp auth"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := emailtest.NewServer(t, tt.server)
+			config := SMTPConfig{
+				Host:      server.Host,
+				Port:      server.Port,
+				Auth:      AuthNone,
+				TLS:       TLSNone,
+				TLSConfig: &tls.Config{ServerName: server.Host, RootCAs: server.RootCAs()},
+				Timeout:   5 * time.Second,
+			}
+			if tt.config != nil {
+				tt.config(&config)
+			}
+
+			err := NewSMTPTransport(config).Check(context.Background())
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("Check() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Check() = %v, want an error about %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckUnreachable(t *testing.T) {
+	server := emailtest.NewServer(t, emailtest.Options{})
+	config := SMTPConfig{Host: server.Host, Port: server.Port, Timeout: time.Second}
+	// nothing listens once the server is gone
+	server.Close()
+
+	if err := NewSMTPTransport(config).Check(context.Background()); err == nil || !strings.Contains(err.Error(), "smtp dial") {
+		t.Fatalf("Check() = %v, want a dial error", err)
+	}
+}
+
+var tokenPattern = regexp.MustCompile(`\[token-(\d+)\]`)
+
+// TestSendConcurrent sends from many goroutines through one transport, each
+// one reusing its recipients slice while the others run. Run it with -race.
+func TestSendConcurrent(t *testing.T) {
+	server := emailtest.NewServer(t, emailtest.Options{})
+	transport := NewSMTPTransport(SMTPConfig{
+		Host: server.Host, Port: server.Port, Timeout: 5 * time.Second,
+		From: mail.Address{Name: "Reports", Address: "reports@example.com"},
+	})
+
+	const senders = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, senders)
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			to := []mail.Address{{Name: fmt.Sprintf("Customer %d", i), Address: fmt.Sprintf("customer-%d@example.com", i)}}
+			msg := Message{
+				To:      to,
+				Subject: fmt.Sprintf("Resumen [token-%d]", i),
+				HTML:    fmt.Sprintf("<p>saldo [token-%d]</p>", i),
+				Text:    fmt.Sprintf("saldo [token-%d]", i),
+			}
+			errs <- transport.Send(context.Background(), msg)
+			to[0].Address = "changed@example.com"
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+
+	assertOwnMessages(t, server.Messages(), senders)
+}
+
+// assertOwnMessages checks that each of the count messages went to the
+// recipient of its token, with only its token in headers and body.
+func assertOwnMessages(t *testing.T, messages []emailtest.Message, count int) {
+	t.Helper()
+	if len(messages) != count {
+		t.Fatalf("server received %d messages, want %d", len(messages), count)
+	}
+	seen := make(map[string]bool, count)
+	for _, received := range messages {
+		parsed, err := mail.ReadMessage(strings.NewReader(received.Data))
+		if err != nil {
+			t.Fatalf("reading message: %v", err)
+		}
+		subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+		if err != nil {
+			t.Fatalf("decoding subject: %v", err)
+		}
+		match := tokenPattern.FindStringSubmatch(subject)
+		if match == nil {
+			t.Fatalf("subject %q has no token", subject)
+		}
+		token := match[1]
+		seen[token] = true
+
+		recipient := "customer-" + token + "@example.com"
+		if len(received.To) != 1 || received.To[0] != recipient {
+			t.Errorf("message %s was sent to %v, want %s", token, received.To, recipient)
+		}
+		to, err := parsed.Header.AddressList("To")
+		if err != nil || len(to) != 1 || to[0].Address != recipient {
+			t.Errorf("message %s has To %v, want %s", token, parsed.Header.Get("To"), recipient)
+		}
+		for _, other := range tokenPattern.FindAllStringSubmatch(received.Data, -1) {
+			if other[1] != token {
+				t.Errorf("message %s carries token %s", token, other[1])
+			}
+		}
+		if n := len(tokenPattern.FindAllString(received.Data, -1)); n < 2 {
+			t.Errorf("message %s carries its token %d times, want the subject and the bodies", token, n)
+		}
+	}
+	if len(seen) != count {
+		t.Errorf("received %d distinct messages, want %d", len(seen), count)
+	}
+}
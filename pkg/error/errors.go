@@ -1,17 +1,85 @@
 package error
 
-import "errors"
+// Kind classifies the errors of the catalog, the API answers each kind with
+// its own status.
+type Kind string
+
+const (
+	KindBadRequest  Kind = "bad_request"
+	KindValidation  Kind = "validation"
+	KindNotFound    Kind = "not_found"
+	KindConflict    Kind = "conflict"
+	KindUnavailable Kind = "dependency_unavailable"
+	KindInternal    Kind = "internal"
+)
+
+// Error is an error of the catalog. Code is stable, clients may rely on it,
+// and Message is safe to show them. Cause is what went wrong underneath, it
+// only reaches the logs unless the request itself is at fault.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Cause   error
+}
 
 var (
-	ErrReadingBody = errors.New("error reading body")
+	ErrReadingBody     = New(KindBadRequest, "reading_body", "error reading body")
+	ErrInvalidBody     = New(KindBadRequest, "invalid_body", "malformed request body")
+	ErrMissingID       = New(KindBadRequest, "missing_id", "id null")
+	ErrInvalidParam    = New(KindBadRequest, "invalid_parameter", "invalid query parameter")
+	ErrInvalidCursor   = New(KindBadRequest, "invalid_cursor", "invalid cursor")
+	ErrInvalidProfile  = New(KindBadRequest, "invalid_profile", "unknown import profile")
+	ErrInvalidFile     = New(KindBadRequest, "invalid_file", "unreadable csv file")
+	ErrValidation      = New(KindValidation, "validation_failed", "invalid request")
+	ErrNotFound        = New(KindNotFound, "not_found", "not found")
+	ErrAlreadyReversed = New(KindConflict, "already_reversed", "transaction already reversed")
+
+	ErrDuplicateTransfer    = New(KindConflict, "duplicate_transfer", "transfer already registered")
+	ErrDuplicateEmail       = New(KindConflict, "duplicate_email", "email already registered")
+	ErrProcessingInProgress = New(KindConflict, "processing_in_progress", "files are already being processed")
+
+	ErrUnavailable = New(KindUnavailable, "dependency_unavailable", "a service the request depends on is unavailable, try again later")
+	ErrInternal    = New(KindInternal, "internal_error", "internal error")
 )
 
-type (
-	HandlerError struct {
-		Cause error
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// NotFound is ErrNotFound naming what was not found.
+func NotFound(resource string) *Error {
+	return ErrNotFound.WithMessage(resource + " not found")
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
 	}
-)
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is matches errors of the same code, so the copies made by Wrap and
+// WithMessage still match the catalog entry.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap returns a copy of the error caused by cause.
+func (e *Error) Wrap(cause error) *Error {
+	wrapped := *e
+	wrapped.Cause = cause
+	return &wrapped
+}
 
-func (e HandlerError) Error() string {
-	return e.Cause.Error()
+// WithMessage returns a copy of the error with a more precise message.
+func (e *Error) WithMessage(message string) *Error {
+	wrapped := *e
+	wrapped.Message = message
+	return &wrapped
 }
@@ -0,0 +1,42 @@
+package error
+
+import "strings"
+
+type (
+	// FieldError tells why the value of one field of a request was refused.
+	FieldError struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	}
+
+	// ValidationError gathers every field error of a request, so they can be
+	// fixed at once.
+	ValidationError struct {
+		Fields []FieldError `json:"fields"`
+	}
+)
+
+func (e ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Field+": "+field.Message)
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns the validation error, nil when no field was refused.
+func (e ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Is makes every validation error match ErrValidation.
+func (e ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
@@ -0,0 +1,152 @@
+// Package i18n holds the message catalogs and the locale aware formatting of
+// dates, months and amounts used by the reports.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultLocale = "en"
+
+//go:embed locales/*.json
+var locales embed.FS
+
+type (
+	catalog struct {
+		Months    [12]string        `json:"months"`
+		Decimal   string            `json:"decimal"`
+		Thousands string            `json:"thousands"`
+		Currency  string            `json:"currency"`
+		Date      string            `json:"date"`
+		Period    string            `json:"period"`
+		Messages  map[string]string `json:"messages"`
+	}
+
+	Localizer struct {
+		locale  string
+		catalog *catalog
+	}
+)
+
+var catalogs = loadCatalogs()
+
+func loadCatalogs() map[string]*catalog {
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	loaded := make(map[string]*catalog, len(entries))
+	for _, entry := range entries {
+		data, err := locales.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		c := new(catalog)
+		if err = json.Unmarshal(data, c); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", entry.Name(), err))
+		}
+		loaded[strings.TrimSuffix(entry.Name(), ".json")] = c
+	}
+	return loaded
+}
+
+// Supported reports whether there is a catalog for locale. Region subtags
+// are ignored, so "es-AR" is supported when "es" is.
+func Supported(locale string) bool {
+	_, ok := catalogs[language(locale)]
+	return ok
+}
+
+// For returns the localizer for locale, falling back to DefaultLocale.
+func For(locale string) Localizer {
+	lang := language(locale)
+	c, ok := catalogs[lang]
+	if !ok {
+		lang, c = DefaultLocale, catalogs[DefaultLocale]
+	}
+	return Localizer{locale: lang, catalog: c}
+}
+
+func language(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return locale
+}
+
+func (l Localizer) Locale() string {
+	return l.locale
+}
+
+// T returns the message for key formatted with args. Keys missing in the
+// catalog fall back to the default locale and then to the key itself.
+func (l Localizer) T(key string, args ...any) string {
+	msg, ok := l.catalog.Messages[key]
+	if !ok {
+		if msg, ok = catalogs[DefaultLocale].Messages[key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+func (l Localizer) Month(month time.Month) string {
+	return l.catalog.Months[month-1]
+}
+
+func (l Localizer) Date(t time.Time) string {
+	return strings.NewReplacer(
+		"{day}", strconv.Itoa(t.Day()),
+		"{month}", l.Month(t.Month()),
+		"{year}", strconv.Itoa(t.Year()),
+	).Replace(l.catalog.Date)
+}
+
+func (l Localizer) Period(year int, month time.Month) string {
+	return strings.NewReplacer(
+		"{month}", l.Month(month),
+		"{year}", strconv.Itoa(year),
+	).Replace(l.catalog.Period)
+}
+
+// Number formats amount with two decimals and the locale separators.
+func (l Localizer) Number(amount float64) string {
+	sign := ""
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	if amount < 0 && cents > 0 {
+		sign = "-"
+	}
+	integer := strconv.FormatInt(cents/100, 10)
+
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteString(l.catalog.Thousands)
+		}
+		sb.WriteRune(digit)
+	}
+	sb.WriteString(l.catalog.Decimal)
+	sb.WriteString(fmt.Sprintf("%02d", cents%100))
+	return sb.String()
+}
+
+func (l Localizer) Money(amount float64) string {
+	return strings.ReplaceAll(l.catalog.Currency, "{amount}", l.Number(amount))
+}
+
+// DecimalSeparator is exposed so tabular exports can pick a field delimiter
+// that does not clash with the amounts.
+func (l Localizer) DecimalSeparator() string {
+	return l.catalog.Decimal
+}
@@ -0,0 +1,250 @@
+// Package ingest parses the amounts and timestamps found in bank exports,
+// following the conventions described by an import profile.
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LayoutUnix reads epoch seconds or, for values of 1e11 and above, epoch
+	// milliseconds; 1e11 seconds is still more than 3000 years away. Only 9
+	// or 10 digit seconds and 12 or 13 digit milliseconds are taken as epoch,
+	// so that years or compact dates such as 20231005 are not read as 1970.
+	LayoutUnix = "unix"
+
+	DefaultProfile = "default"
+
+	millisecondsFrom = 1e11
+)
+
+var (
+	ErrUnknownProfile = errors.New("unknown import profile")
+
+	isoLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+)
+
+type (
+	// Profile describes the formats of one kind of export. DateLayouts are Go
+	// time layouts, or LayoutUnix, tried in order; layouts without a zone are
+	// read in Timezone, UTC when empty.
+	Profile struct {
+		Name        string   `json:"name"`
+		Delimiter   string   `json:"delimiter"`
+		SkipHeader  bool     `json:"skip_header"`
+		DateLayouts []string `json:"date_layouts"`
+		Decimal     string   `json:"decimal"`
+		Thousands   string   `json:"thousands"`
+		Timezone    string   `json:"timezone"`
+	}
+
+	Profiles map[string]Profile
+)
+
+// DefaultProfiles returns the built-in profiles: default (ISO-8601 or epoch
+// dates, dot decimals), eu (dd/mm/yyyy dates, comma decimals, semicolon
+// separated) and us (mm/dd/yyyy dates, dot decimals). Calendar layouts are
+// tried before epoch.
+func DefaultProfiles() Profiles {
+	return Profiles{
+		DefaultProfile: {
+			Name:        DefaultProfile,
+			Delimiter:   ",",
+			DateLayouts: append(append([]string{}, isoLayouts...), LayoutUnix),
+			Decimal:     ".",
+			Thousands:   ",",
+		},
+		"eu": {
+			Name:        "eu",
+			Delimiter:   ";",
+			DateLayouts: append(append([]string{"02/01/2006 15:04:05", "02/01/2006", "02.01.2006"}, isoLayouts...), LayoutUnix),
+			Decimal:     ",",
+			Thousands:   ".",
+		},
+		"us": {
+			Name:        "us",
+			Delimiter:   ",",
+			DateLayouts: append(append([]string{"01/02/2006 15:04:05", "01/02/2006"}, isoLayouts...), LayoutUnix),
+			Decimal:     ".",
+			Thousands:   ",",
+		},
+	}
+}
+
+// LoadProfiles adds the profiles of a JSON file, an array of Profile, to the
+// built-in ones. A profile with the name of a built-in one replaces it.
+func LoadProfiles(path string) (Profiles, error) {
+	profiles := DefaultProfiles()
+	if path == "" {
+		return profiles, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var custom []Profile
+	if err = json.Unmarshal(content, &custom); err != nil {
+		return nil, fmt.Errorf("ingest: reading %s: %w", path, err)
+	}
+	for _, profile := range custom {
+		if err = profile.validate(); err != nil {
+			return nil, err
+		}
+		profiles[profile.Name] = profile
+	}
+	return profiles, nil
+}
+
+// Get returns the profile called name, the default one when name is empty.
+func (p Profiles) Get(name string) (Profile, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	profile, ok := p[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("%w %q", ErrUnknownProfile, name)
+	}
+	return profile, nil
+}
+
+func (p Profile) validate() error {
+	switch {
+	case p.Name == "":
+		return errors.New("ingest: profile without name")
+	case len(p.DateLayouts) == 0:
+		return fmt.Errorf("ingest: profile %q has no date layouts", p.Name)
+	case len([]rune(p.Decimal)) != 1:
+		return fmt.Errorf("ingest: profile %q needs a one character decimal separator", p.Name)
+	case p.Thousands == p.Decimal:
+		return fmt.Errorf("ingest: profile %q uses the same decimal and thousands separator", p.Name)
+	case p.Delimiter != "" && len([]rune(p.Delimiter)) != 1:
+		return fmt.Errorf("ingest: profile %q needs a one character delimiter", p.Name)
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("ingest: profile %q: %w", p.Name, err)
+	}
+	return nil
+}
+
+// Comma returns the field delimiter of the profile for encoding/csv.
+func (p Profile) Comma() rune {
+	if p.Delimiter == "" {
+		return ','
+	}
+	return []rune(p.Delimiter)[0]
+}
+
+// ParseAmount reads a signed amount such as "-20.46", "+1,234.50",
+// "1.234,50" or "(20.46)", parentheses meaning a negative amount. Thousands
+// separators must split the integer part in groups of three digits.
+func (p Profile) ParseAmount(input string) (float64, error) {
+	value := strings.TrimSpace(input)
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	} else if value != "" && (value[0] == '-' || value[0] == '+') {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	integer, fraction, hasFraction := strings.Cut(value, p.decimal())
+	if p.Thousands != "" && strings.Contains(integer, p.Thousands) {
+		groups := strings.Split(integer, p.Thousands)
+		for i, group := range groups {
+			if (i == 0 && (group == "" || len(group) > 3)) || (i > 0 && len(group) != 3) {
+				return 0, fmt.Errorf("bad amount %q", input)
+			}
+		}
+		integer = strings.Join(groups, "")
+	}
+	if !digits(integer) || (hasFraction && !digits(fraction)) || (integer == "" && fraction == "") {
+		return 0, fmt.Errorf("bad amount %q", input)
+	}
+
+	normalized := integer
+	if hasFraction {
+		normalized += "." + fraction
+	}
+	amount, err := strconv.ParseFloat(normalized, 64)
+	if err != nil || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("bad amount %q", input)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// ParseTime reads value with the first layout of the profile that accepts it.
+func (p Profile) ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	location := time.UTC
+	if p.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(p.Timezone); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	for _, layout := range p.DateLayouts {
+		if layout == LayoutUnix {
+			if !epochLength(value) {
+				continue
+			}
+			if parsed, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return Epoch(parsed), nil
+			}
+			continue
+		}
+		if parsed, err := time.ParseInLocation(layout, value, location); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad date %q", value)
+}
+
+// Epoch reads epoch seconds, or epoch milliseconds from 1e11 on.
+func Epoch(value int64) time.Time {
+	if value >= millisecondsFrom || value <= -millisecondsFrom {
+		return time.UnixMilli(value).UTC()
+	}
+	return time.Unix(value, 0).UTC()
+}
+
+func (p Profile) decimal() string {
+	if p.Decimal == "" {
+		return "."
+	}
+	return p.Decimal
+}
+
+// epochLength tells whether value has as many digits as epoch seconds or
+// milliseconds between 1973 and 2286.
+func epochLength(value string) bool {
+	if !digits(value) {
+		return false
+	}
+	switch len(value) {
+	case 9, 10, 12, 13:
+		return true
+	}
+	return false
+}
+
+func digits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
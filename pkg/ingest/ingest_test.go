@@ -0,0 +1,126 @@
+package ingest
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseAmount(t *testing.T) {
+	profiles := DefaultProfiles()
+	tests := []struct {
+		profile string
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{profile: "default", input: "-20.46", want: -20.46},
+		{profile: "default", input: "+1,234.50", want: 1234.5},
+		{profile: "default", input: "1,234,567", want: 1234567},
+		{profile: "default", input: "(20.46)", want: -20.46},
+		{profile: "default", input: ".5", want: 0.5},
+		{profile: "default", input: "1,5", wantErr: true},
+		{profile: "default", input: "1,2,3", wantErr: true},
+		{profile: "default", input: "1234,567", wantErr: true},
+		{profile: "default", input: ",123", wantErr: true},
+		{profile: "default", input: "12,34.5", wantErr: true},
+		{profile: "default", input: "", wantErr: true},
+		{profile: "eu", input: "1.234,50", want: 1234.5},
+		{profile: "eu", input: "1.5", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile+" "+tt.input, func(t *testing.T) {
+			got, err := profiles[tt.profile].ParseAmount(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseAmount(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("ParseAmount(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	profiles := DefaultProfiles()
+	tests := []struct {
+		profile string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{profile: "default", input: "1697823898", want: time.Unix(1697823898, 0).UTC()},
+		{profile: "default", input: "1697823898123", want: time.UnixMilli(1697823898123).UTC()},
+		{profile: "default", input: "2023-10-05", want: time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{profile: "default", input: "2023-10-05T10:00:00-03:00", want: time.Date(2023, 10, 5, 13, 0, 0, 0, time.UTC)},
+		{profile: "default", input: "2023", wantErr: true},
+		{profile: "default", input: "20231005", wantErr: true},
+		{profile: "eu", input: "05/10/2023", want: time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC)},
+		{profile: "us", input: "10/05/2023", want: time.Date(2023, 10, 5, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile+" "+tt.input, func(t *testing.T) {
+			got, err := profiles[tt.profile].ParseTime(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseTime(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Fatalf("ParseTime(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParseAmount(f *testing.F) {
+	for _, seed := range []string{"-20.46", "+1,234.50", "(20.46)", "1,5", "1,2,3", "1,234,567.89", ".5", "", "1e5", "(", "--1"} {
+		f.Add(seed)
+	}
+	profile := DefaultProfiles()[DefaultProfile]
+	f.Fuzz(func(t *testing.T, input string) {
+		amount, err := profile.ParseAmount(input)
+		if err != nil {
+			return
+		}
+		if math.IsNaN(amount) || math.IsInf(amount, 0) {
+			t.Fatalf("ParseAmount(%q) = %v", input, amount)
+		}
+		// the amount written plainly reads back the same
+		formatted := strconv.FormatFloat(amount, 'f', -1, 64)
+		again, err := profile.ParseAmount(formatted)
+		if err != nil || again != amount {
+			t.Fatalf("ParseAmount(%q) = %v, its round trip %q = %v, %v", input, amount, formatted, again, err)
+		}
+	})
+}
+
+func FuzzParseTime(f *testing.F) {
+	for _, seed := range []string{"1697823898", "1697823898123", "2023", "20231005", "2023-10-05", "2023-10-05T10:00:00Z",
+		"2023-10-05 10:00:00", "-1", "99999999999999999999"} {
+		f.Add(seed)
+	}
+	profile := DefaultProfiles()[DefaultProfile]
+	f.Fuzz(func(t *testing.T, input string) {
+		parsed, err := profile.ParseTime(input)
+		if err != nil {
+			return
+		}
+		if parsed.Location() != time.UTC {
+			t.Fatalf("ParseTime(%q) = %v, not in UTC", input, parsed)
+		}
+		if digits(input) && !epochLength(input) {
+			t.Fatalf("ParseTime(%q) = %v, digits of no plausible epoch", input, parsed)
+		}
+		formatted := parsed.Format(time.RFC3339Nano)
+		again, err := profile.ParseTime(formatted)
+		if err != nil || !again.Equal(parsed) {
+			t.Fatalf("ParseTime(%q) = %v, its round trip %q = %v, %v", input, parsed, formatted, again, err)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+// Package pdf writes simple text documents in PDF format without any external
+// dependency. It only supports the standard Helvetica fonts, text and lines,
+// which is enough for tabular reports.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// A4 size in points.
+	PageWidth  = 595.28
+	PageHeight = 841.89
+
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+type (
+	Document struct {
+		pages []*bytes.Buffer
+	}
+)
+
+func New() *Document {
+	d := &Document{}
+	d.AddPage()
+	return d
+}
+
+// AddPage starts a new page, following calls draw on it.
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, new(bytes.Buffer))
+}
+
+// Text draws s with its baseline starting at (x, y), measured in points from
+// the top left corner of the page.
+func (d *Document) Text(x, y, size float64, s string) {
+	d.text(fontRegular, x, y, size, s)
+}
+
+// BoldText works like Text using the bold font.
+func (d *Document) BoldText(x, y, size float64, s string) {
+	d.text(fontBold, x, y, size, s)
+}
+
+// Line draws a thin line from (x1, y1) to (x2, y2).
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.current(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, PageHeight-y1, x2, PageHeight-y2)
+}
+
+func (d *Document) text(font string, x, y, size float64, s string) {
+	fmt.Fprintf(d.current(), "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, PageHeight-y, escape(s))
+}
+
+func (d *Document) current() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Bytes renders the whole document.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	_, _ = d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1 to 4 are fixed: catalog, page tree and the two fonts. Each
+	// page then takes two objects, the page itself and its content stream.
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, fontRegular, fontBold, 6+i*2))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// escape converts s to WinAnsi and escapes the characters that are special
+// inside a PDF string. Runes outside Latin-1 are replaced by '?'.
+func escape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '€':
+			sb.WriteByte(0x80)
+		case r < 0x20:
+			sb.WriteByte(' ')
+		case r < 0x7f || (r >= 0xa0 && r <= 0xff):
+			sb.WriteByte(byte(r))
+		default:
+			sb.WriteByte('?')
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,209 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// parsed is the structure read back from a document: the objects by number
+// and the trailer.
+type parsed struct {
+	objects map[int]string
+	trailer string
+}
+
+// parse reads data back through its cross-reference table, checking that
+// every offset points at the object it lists and that the stream lengths
+// are right.
+func parse(t *testing.T, data []byte) parsed {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("missing header or end of file marker")
+	}
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("missing startxref")
+	}
+	start, _ := strconv.Atoi(string(match[1]))
+	if start >= len(data) || !bytes.HasPrefix(data[start:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", start)
+	}
+
+	xref, trailer, ok := strings.Cut(string(data[start:]), "trailer\n")
+	if !ok {
+		t.Fatalf("missing trailer")
+	}
+	lines := strings.Split(strings.TrimSuffix(xref, "\n"), "\n")
+	var first, count int
+	if _, err := fmt.Sscanf(lines[1], "%d %d", &first, &count); err != nil || first != 0 {
+		t.Fatalf("bad xref subsection %q", lines[1])
+	}
+	entries := lines[2:]
+	if len(entries) != count {
+		t.Fatalf("xref lists %d entries, its header says %d", len(entries), count)
+	}
+	if entries[0] != "0000000000 65535 f " {
+		t.Fatalf("bad free entry %q", entries[0])
+	}
+	if !strings.Contains(trailer, fmt.Sprintf("/Size %d ", count)) {
+		t.Fatalf("trailer %q does not have /Size %d", trailer, count)
+	}
+
+	objects := make(map[int]string)
+	for number := 1; number < count; number++ {
+		entry := entries[number]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("bad xref entry %q", entry)
+		}
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil || offset >= start {
+			t.Fatalf("bad offset in xref entry %q", entry)
+		}
+		header := fmt.Sprintf("%d 0 obj\n", number)
+		if !bytes.HasPrefix(data[offset:], []byte(header)) {
+			t.Fatalf("offset %d of object %d points at %q", offset, number, data[offset:min(offset+20, len(data))])
+		}
+		body, _, ok := strings.Cut(string(data[offset+len(header):]), "\nendobj\n")
+		if !ok {
+			t.Fatalf("object %d does not end", number)
+		}
+		objects[number] = body
+	}
+
+	length := regexp.MustCompile(`^<< /Length (\d+) >>\nstream\n`)
+	for number, body := range objects {
+		if match := length.FindStringSubmatch(body); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			if stream := strings.TrimSuffix(body[len(match[0]):], "endstream"); len(stream) != n {
+				t.Fatalf("stream of object %d is %d bytes long, /Length says %d", number, len(stream), n)
+			}
+		}
+	}
+	return parsed{objects: objects, trailer: trailer}
+}
+
+// pages returns the content streams of the pages in order.
+func (p parsed) pages(t *testing.T) []string {
+	t.Helper()
+	root := regexp.MustCompile(`/Root (\d+) 0 R`).FindStringSubmatch(p.trailer)
+	if root == nil {
+		t.Fatalf("trailer without /Root")
+	}
+	catalog := p.objects[atoi(root[1])]
+	tree := regexp.MustCompile(`/Type /Catalog /Pages (\d+) 0 R`).FindStringSubmatch(catalog)
+	if tree == nil {
+		t.Fatalf("bad catalog %q", catalog)
+	}
+	kids := regexp.MustCompile(`/Kids \[([^\]]*)\] /Count (\d+)`).FindStringSubmatch(p.objects[atoi(tree[1])])
+	if kids == nil {
+		t.Fatalf("bad page tree %q", p.objects[atoi(tree[1])])
+	}
+	references := regexp.MustCompile(`(\d+) 0 R`).FindAllStringSubmatch(kids[1], -1)
+	if len(references) != atoi(kids[2]) {
+		t.Fatalf("page tree has %d kids, /Count says %s", len(references), kids[2])
+	}
+
+	var contents []string
+	for _, reference := range references {
+		page := p.objects[atoi(reference[1])]
+		stream := regexp.MustCompile(`/Type /Page .*/Contents (\d+) 0 R`).FindStringSubmatch(page)
+		if stream == nil {
+			t.Fatalf("bad page %q", page)
+		}
+		content, _, _ := strings.Cut(p.objects[atoi(stream[1])], "endstream")
+		_, content, _ = strings.Cut(content, "stream\n")
+		contents = append(contents, content)
+	}
+	return contents
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func TestDocumentStructure(t *testing.T) {
+	doc := New()
+	doc.BoldText(50, 50, 18, "Summary")
+	doc.Line(50, 54, 545, 54)
+	doc.Text(50, 70, 11, "Balance: 1.534,50 €")
+
+	pages := parse(t, doc.Bytes()).pages(t)
+	if len(pages) != 1 {
+		t.Fatalf("%d pages, want 1", len(pages))
+	}
+	for _, want := range []string{
+		"BT /F2 18.00 Tf 50.00 791.89 Td (Summary) Tj ET\n",
+		"0.5 w 50.00 787.89 m 545.00 787.89 l S\n",
+		"BT /F1 11.00 Tf 50.00 771.89 Td (Balance: 1.534,50 \x80) Tj ET\n",
+	} {
+		if !strings.Contains(pages[0], want) {
+			t.Errorf("page does not contain %q:\n%s", want, pages[0])
+		}
+	}
+}
+
+func TestDocumentPages(t *testing.T) {
+	doc := New()
+	for i := 1; i <= 3; i++ {
+		if i > 1 {
+			doc.AddPage()
+		}
+		doc.Text(50, 50, 10, fmt.Sprintf("page %d", i))
+	}
+
+	data := doc.Bytes()
+	pages := parse(t, data).pages(t)
+	if len(pages) != 3 {
+		t.Fatalf("%d pages, want 3", len(pages))
+	}
+	for i, page := range pages {
+		if want := fmt.Sprintf("(page %d) Tj", i+1); !strings.Contains(page, want) {
+			t.Errorf("page %d does not contain %q:\n%s", i+1, want, page)
+		}
+	}
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	if err != nil || n != int64(len(data)) || !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("WriteTo wrote %d bytes, %v, want the %d of Bytes", n, err, len(data))
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "plain text", want: "plain text"},
+		{in: "(parentheses)", want: `\(parentheses\)`},
+		{in: `back\slash`, want: `back\\slash`},
+		{in: `f(x) = \(y)`, want: `f\(x\) = \\\(y\)`},
+		{in: "unbalanced (", want: `unbalanced \(`},
+		{in: "tab\tand\nnewline", want: "tab and newline"},
+		{in: "Año €5", want: "A\xf1o \x805"},
+		{in: "emoji 🙂", want: "emoji ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := escape(tt.in); got != tt.want {
+				t.Fatalf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextEscaped(t *testing.T) {
+	doc := New()
+	doc.Text(50, 50, 10, `a (b) c\d`)
+
+	pages := parse(t, doc.Bytes()).pages(t)
+	if want := `(a \(b\) c\\d) Tj`; !strings.Contains(pages[0], want) {
+		t.Fatalf("page does not contain %q:\n%s", want, pages[0])
+	}
+}